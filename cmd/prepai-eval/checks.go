@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"prepai.app/internal"
+	"prepai.app/providers"
+)
+
+// Check is one automated assertion on the output of a case.
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func check(name string, passed bool, format string, args ...any) Check {
+	return Check{Name: name, Passed: passed, Detail: fmt.Sprintf(format, args...)}
+}
+
+func between(value int64, low int64, high int64) bool {
+	return value >= low && value <= high
+}
+
+// Truncated responses are the most common failure of long generations.
+func jsonChecks(calls []providers.Response) Check {
+	invalid := 0
+	for _, call := range calls {
+		if !json.Valid([]byte(call.Text)) {
+			invalid++
+		}
+	}
+
+	return check("json_complete", len(calls) > 0 && invalid == 0, "%v of %v responses are not complete JSON", invalid, len(calls))
+}
+
+func examChecks(exam internal.ExamResponse, difficulty string, examType string) []Check {
+	expected := internal.ExpectedExamQuestions(difficulty)
+
+	low, high := examOptions(examType)
+	options := fmt.Sprint(low)
+	if low != high {
+		options = fmt.Sprintf("%v to %v", low, high)
+	}
+
+	var badIndexes, badOptions []string
+	for i, question := range exam.Questions {
+		if !validAnswerKey(question, examType) {
+			badIndexes = append(badIndexes, fmt.Sprint(i))
+		}
+		if !between(int64(len(question.Options)), low, high) {
+			badOptions = append(badOptions, fmt.Sprint(i))
+		}
+	}
+
+	return []Check{
+		check("question_count", len(exam.Questions) == expected, "expected %v questions for %v, got %v", expected, difficulty, len(exam.Questions)),
+		check("correct_index", len(exam.Questions) > 0 && len(badIndexes) == 0, "questions with an invalid answer key: [%v]", strings.Join(badIndexes, ", ")),
+		check("option_count", len(exam.Questions) > 0 && len(badOptions) == 0, "questions without %v options: [%v]", options, strings.Join(badOptions, ", ")),
+	}
+}
+
+// How many options questions of the exam type have.
+func examOptions(examType string) (int64, int64) {
+	switch examType {
+	case internal.TrueFalse:
+		return 2, 2
+	case internal.MultipleSelect:
+		return 5, 5
+	case internal.Ordering:
+		return 3, 6
+	case internal.ShortAnswer:
+		return 0, 0
+	default:
+		return 4, 4
+	}
+}
+
+func validAnswerKey(question internal.ExamQuestion, examType string) bool {
+	options := int64(len(question.Options))
+
+	switch examType {
+	case internal.MultipleSelect:
+		if len(question.CorrectOptions) == 0 {
+			return false
+		}
+		for _, correct := range question.CorrectOptions {
+			if correct < 0 || correct >= options {
+				return false
+			}
+		}
+		return true
+	case internal.Ordering:
+		return int64(len(question.CorrectOrder)) == options
+	case internal.ShortAnswer:
+		return strings.TrimSpace(question.ReferenceAnswer) != ""
+	default:
+		return question.Correct >= 0 && question.Correct < options
+	}
+}
+
+func interviewChecks(interview internal.InterviewResponse) []Check {
+	return []Check{
+		check("question_count", len(interview.Questions) == 5, "expected 5 questions, got %v", len(interview.Questions)),
+	}
+}
+
+func feedbackChecks(feedback internal.InterviewFeedbackResponse, responses int) []Check {
+	var outOfRange []string
+	for i, item := range feedback.Feedbacks {
+		if item.Score < 1 || item.Score > 10 {
+			outOfRange = append(outOfRange, fmt.Sprint(i))
+		}
+	}
+
+	return []Check{
+		check("feedback_count", len(feedback.Feedbacks) == responses, "expected %v feedbacks, got %v", responses, len(feedback.Feedbacks)),
+		check("score_range", len(feedback.Feedbacks) > 0 && len(outOfRange) == 0, "feedbacks with a score outside 1-10: [%v]", strings.Join(outOfRange, ", ")),
+	}
+}
+
+func resumeChecks(analysis internal.ResumeAnalyzerResponse) []Check {
+	metrics := analysis.Metrics
+	return []Check{
+		check("overall_score_range", between(analysis.OverallScore, 1, 100), "overall_score %v must be 1-100", analysis.OverallScore),
+		check("ats_score_range", between(metrics.AtsMatchScore, 1, 100), "ats_match_score %v must be 1-100", metrics.AtsMatchScore),
+		check("clarity_score_range", between(metrics.ClarityScore, 1, 10), "clarity_score %v must be 1-10", metrics.ClarityScore),
+	}
+}
+
+func questionChecks(analysis internal.QuestionAnalysisResponse) []Check {
+	validType := false
+	switch analysis.Type {
+	case "Behavioral", "Technical", "HR", "Situational", "Other":
+		validType = true
+	}
+
+	return []Check{
+		check("type", validType, "type is %q", analysis.Type),
+		check("difficulty", internal.ExpectedExamQuestions(analysis.Difficulty) > 0, "difficulty is %q", analysis.Difficulty),
+		check("key_points", len(analysis.IdealAnswer.KeyPoints) > 0, "%v key points", len(analysis.IdealAnswer.KeyPoints)),
+	}
+}
+
+func modulesChecks(modules internal.ModuleResponse) []Check {
+	var last *internal.Module
+	for i := range modules.Modules {
+		if last == nil || modules.Modules[i].Order > last.Order {
+			last = &modules.Modules[i]
+		}
+	}
+
+	lastTitle := ""
+	if last != nil {
+		lastTitle = last.Title
+	}
+
+	return []Check{
+		check("module_count", between(int64(len(modules.Modules)), 10, 12), "expected 10-12 modules, got %v", len(modules.Modules)),
+		check("final_challenge", strings.EqualFold(strings.TrimSpace(lastTitle), "final challenge"), "last module is %q", lastTitle),
+	}
+}
+
+func stepsChecks(steps internal.StepsResponse) []Check {
+	return []Check{
+		check("step_count", between(int64(len(steps.Steps)), 8, 10), "expected 8-10 steps, got %v", len(steps.Steps)),
+	}
+}
+
+func lessonChecks(lesson internal.LessonResponse) []Check {
+	return []Check{
+		check("section_count", between(int64(len(lesson.Sections)), 3, 5), "expected 3-5 sections, got %v", len(lesson.Sections)),
+		check("key_takeaways", len(lesson.KeyTakeaways) > 0, "%v key takeaways", len(lesson.KeyTakeaways)),
+	}
+}
+
+// A translation that moves options around breaks the answer key.
+func translateChecks(source internal.ExamResponse, exam internal.ExamResponse) []Check {
+	var badOptions, badIndexes []string
+	for i, question := range exam.Questions {
+		if i >= len(source.Questions) {
+			break
+		}
+		if len(question.Options) != len(source.Questions[i].Options) {
+			badOptions = append(badOptions, fmt.Sprint(i))
+		}
+		if question.Correct != source.Questions[i].Correct {
+			badIndexes = append(badIndexes, fmt.Sprint(i))
+		}
+	}
+
+	return []Check{
+		check("question_count", len(exam.Questions) == len(source.Questions), "expected %v questions, got %v", len(source.Questions), len(exam.Questions)),
+		check("option_count", len(exam.Questions) > 0 && len(badOptions) == 0, "questions with a different number of options: [%v]", strings.Join(badOptions, ", ")),
+		check("correct_kept", len(exam.Questions) > 0 && len(badIndexes) == 0, "questions whose correct index changed: [%v]", strings.Join(badIndexes, ", ")),
+	}
+}
+
+func gradeChecks(grades internal.ShortAnswerGrades, answers int) []Check {
+	var outOfRange []string
+	for i, grade := range grades.Grades {
+		if grade.Score < 0 || grade.Score > 10 {
+			outOfRange = append(outOfRange, fmt.Sprint(i))
+		}
+	}
+
+	return []Check{
+		check("grade_count", len(grades.Grades) == answers, "expected %v grades, got %v", answers, len(grades.Grades)),
+		check("score_range", len(grades.Grades) > 0 && len(outOfRange) == 0, "grades with a score outside 0-10: [%v]", strings.Join(outOfRange, ", ")),
+	}
+}
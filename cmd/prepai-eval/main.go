@@ -0,0 +1,192 @@
+// prepai-eval runs every generator against a suite of fixture inputs and
+// scores the outputs with automated checks. The JSON report it writes can be
+// diffed between prompt or model changes before rolling them out.
+//
+//	go run ./cmd/prepai-eval                      # fake provider, embedded suite
+//	go run ./cmd/prepai-eval -provider env -out report.json
+//	go run ./cmd/prepai-eval -prompts ./prompts-next -provider env
+//	go run ./cmd/prepai-eval -provider env -record ./cassettes
+//	go run ./cmd/prepai-eval -provider replay -cassettes ./cassettes
+//
+// The fake provider answers each case with the responses stored in the suite,
+// env uses the providers configured for the server and replay answers from
+// recorded cassettes, failing on any request that was not recorded. Only
+// cassettes recorded with -provider env show how a model behaves, the ones in
+// providers/testdata/synthetic were recorded from the fake provider.
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"prepai.app/internal"
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+type Summary struct {
+	Cases            int   `json:"cases"`
+	Passed           int   `json:"passed"`
+	Failed           int   `json:"failed"`
+	Checks           int   `json:"checks"`
+	FailedChecks     int   `json:"failed_checks"`
+	Calls            int   `json:"calls"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+}
+
+type Report struct {
+	Provider   string         `json:"provider"`
+	Prompts    map[string]int `json:"prompts"`
+	StartedAt  time.Time      `json:"started_at"`
+	DurationMs int64          `json:"duration_ms"`
+	Summary    Summary        `json:"summary"`
+	Cases      []CaseResult   `json:"cases"`
+}
+
+func main() {
+	suitePath := flag.String("suite", "", "suite file, the embedded fixtures/suite.json by default")
+	providerName := flag.String("provider", "fake", "fake answers with the suite responses, env uses the configured models, replay uses -cassettes")
+	cassettes := flag.String("cassettes", "", "cassette directory replayed by the replay provider")
+	record := flag.String("record", "", "cassette directory every interaction is recorded to")
+	promptsDir := flag.String("prompts", "", "directory with prompt templates overriding the embedded ones")
+	maxRepairs := flag.Int("max-repairs", 2, "times the model is asked to fix an invalid response")
+	chunkSize := flag.Int("chunk-size", 15, "exams with more questions are generated in chunks, 0 disables it")
+	out := flag.String("out", "", "file the report is written to, stdout by default")
+	flag.Parse()
+
+	// Files named by the cases are relative to the suite
+	files, _ := fs.Sub(fixtures, "fixtures")
+	suiteName := "suite.json"
+	if *suitePath != "" {
+		files = os.DirFS(filepath.Dir(*suitePath))
+		suiteName = filepath.Base(*suitePath)
+	}
+
+	cases, err := loadSuite(files, suiteName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	registry := prompts.Embedded()
+	if *promptsDir != "" {
+		registry, err = prompts.Load(*promptsDir)
+		if err != nil {
+			log.Fatalf("failed to load prompts: %v", err)
+		}
+	}
+
+	// The fake provider is refilled with the responses of each case
+	fake := &caseFake{}
+	var provider providers.Provider = fake
+	switch *providerName {
+	case "fake":
+	case "env":
+		provider, err = providers.NewFromEnv()
+		if err != nil {
+			log.Fatalf("failed to set up ai provider: %v", err)
+		}
+	case "replay":
+		if *cassettes == "" {
+			log.Fatal("the replay provider needs -cassettes")
+		}
+		provider, err = providers.NewCassette(*cassettes, providers.CassetteReplay, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown provider %v", *providerName)
+	}
+
+	if *record != "" {
+		provider, err = providers.NewCassette(*record, providers.CassetteRecord, provider)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	report := Report{
+		Provider:  *providerName,
+		Prompts:   make(map[string]int),
+		StartedAt: time.Now(),
+		Cases:     make([]CaseResult, 0, len(cases)),
+	}
+	for _, name := range registry.Names() {
+		report.Prompts[name] = registry.Version(name)
+	}
+
+	for _, c := range cases {
+		fake.Fake = providers.NewFake(c.fakeResponses()...)
+
+		recorder := &recorder{provider: provider}
+		generator := internal.NewGenerator(recorder)
+		generator.Prompts = registry
+		generator.MaxRepairs = *maxRepairs
+		generator.ExamChunkSize = *chunkSize
+
+		result := runCase(context.Background(), generator, recorder, files, c)
+		report.Cases = append(report.Cases, result)
+		report.Summary.add(result)
+
+		status := "ok"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		log.Printf("%-4v %v (%v calls, %vms)", status, result.Name, result.Calls, result.DurationMs)
+	}
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(append(data, '\n'))
+	} else {
+		err = os.WriteFile(*out, data, 0o644)
+		if err != nil {
+			log.Fatalf("failed to write report: %v", err)
+		}
+	}
+
+	if report.Summary.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func (summary *Summary) add(result CaseResult) {
+	summary.Cases++
+	if result.Passed {
+		summary.Passed++
+	} else {
+		summary.Failed++
+	}
+
+	summary.Checks += len(result.Checks)
+	for _, check := range result.Checks {
+		if !check.Passed {
+			summary.FailedChecks++
+		}
+	}
+
+	summary.Calls += result.Calls
+	summary.PromptTokens += result.PromptTokens
+	summary.CompletionTokens += result.CompletionTokens
+}
+
+// caseFake answers with the Fake of the case being run, cases run one at a
+// time so a cassette can wrap it for the whole suite.
+type caseFake struct {
+	*providers.Fake
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"prepai.app/providers"
+)
+
+// recorder keeps every response of the provider during a case, so the report
+// can count calls and tokens and score raw output the generator rejected.
+type recorder struct {
+	provider providers.Provider
+
+	mutex     sync.Mutex
+	responses []providers.Response
+}
+
+func (recorder *recorder) Model(feature string) string {
+	return providers.ModelFor(recorder.provider, feature)
+}
+
+func (recorder *recorder) Generate(ctx context.Context, request providers.Request) (providers.Response, error) {
+	response, err := recorder.provider.Generate(ctx, request)
+	if err != nil {
+		return providers.Response{}, err
+	}
+
+	recorder.mutex.Lock()
+	recorder.responses = append(recorder.responses, response)
+	recorder.mutex.Unlock()
+
+	return response, nil
+}
+
+func (recorder *recorder) calls() []providers.Response {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return append([]providers.Response(nil), recorder.responses...)
+}
+
+// Decodes the last response into output, reports false when there is none or
+// it is not valid JSON.
+func (recorder *recorder) decodeLast(output any) bool {
+	calls := recorder.calls()
+	if len(calls) == 0 {
+		return false
+	}
+
+	return json.Unmarshal([]byte(calls[len(calls)-1].Text), output) == nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"prepai.app/internal"
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+// Case is one generator call of the suite. Responses are what the fake
+// provider answers with, in order, a JSON string is sent as is so truncated
+// or invalid output can be replayed too.
+type Case struct {
+	Name      string            `json:"name"`
+	Feature   string            `json:"feature"`
+	Input     Input             `json:"input"`
+	Responses []json.RawMessage `json:"responses,omitempty"`
+}
+
+// Input holds the arguments of every generator, each feature reads its own.
+type Input struct {
+	Subject         string                           `json:"subject,omitempty"`
+	Difficulty      string                           `json:"difficulty,omitempty"`
+	Type            string                           `json:"type,omitempty"`
+	JobRole         string                           `json:"job_role,omitempty"`
+	JobLevel        string                           `json:"job_level,omitempty"`
+	JobDescription  string                           `json:"job_description,omitempty"`
+	Topics          []string                         `json:"topics,omitempty"`
+	Responses       []internal.UserInterviewResponse `json:"responses,omitempty"`
+	File            string                           `json:"file,omitempty"`
+	Question        string                           `json:"question,omitempty"`
+	ModuleTitle     string                           `json:"module_title,omitempty"`
+	ModuleObjective string                           `json:"module_objective,omitempty"`
+	Title           string                           `json:"title,omitempty"`
+	Language        string                           `json:"language,omitempty"`
+	Exam            *internal.ExamResponse           `json:"exam,omitempty"`
+	Answers         []prompts.ShortAnswer            `json:"answers,omitempty"`
+}
+
+type CaseResult struct {
+	Name             string      `json:"name"`
+	Feature          string      `json:"feature"`
+	Passed           bool        `json:"passed"`
+	Error            string      `json:"error,omitempty"`
+	Prompt           prompts.Ref `json:"prompt"`
+	Calls            int         `json:"calls"`
+	PromptTokens     int64       `json:"prompt_tokens"`
+	CompletionTokens int64       `json:"completion_tokens"`
+	DurationMs       int64       `json:"duration_ms"`
+	Checks           []Check     `json:"checks"`
+}
+
+func loadSuite(files fs.FS, name string) ([]Case, error) {
+	data, err := fs.ReadFile(files, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %v", err)
+	}
+
+	var cases []Case
+	err = json.Unmarshal(data, &cases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse suite: %v", err)
+	}
+
+	return cases, nil
+}
+
+// The texts the fake provider answers the case with.
+func (c Case) fakeResponses() []string {
+	texts := make([]string, len(c.Responses))
+	for i, raw := range c.Responses {
+		if json.Unmarshal(raw, &texts[i]) != nil {
+			texts[i] = string(raw)
+		}
+	}
+
+	return texts
+}
+
+// Runs the case against the generator and scores its output. When the
+// generator gives up, the last response of the model is scored instead so the
+// report shows what was wrong with it.
+func runCase(ctx context.Context, generator *internal.Generator, recorder *recorder, files fs.FS, c Case) CaseResult {
+	result := CaseResult{Name: c.Name, Feature: c.Feature}
+	input := c.Input
+	start := time.Now()
+
+	if input.Language != "" {
+		ctx = internal.WithLanguage(ctx, input.Language)
+	}
+
+	var err error
+	switch c.Feature {
+	case providers.FeatureExam:
+		var exam internal.ExamResponse
+		exam, err = generator.GenerateExam(ctx, input.Subject, input.Difficulty, input.Type)
+		scoreLast(err, recorder, &exam)
+		result.Prompt = exam.Prompt
+		result.Checks = examChecks(exam, input.Difficulty, input.Type)
+	case providers.FeatureInterview:
+		var interview internal.InterviewResponse
+		interview, err = generator.GenerateInterview(ctx, input.JobRole, input.JobLevel, input.Topics)
+		scoreLast(err, recorder, &interview)
+		result.Prompt = interview.Prompt
+		result.Checks = interviewChecks(interview)
+	case providers.FeatureFeedback:
+		var feedback internal.InterviewFeedbackResponse
+		feedback, err = generator.GenerateInterviewFeedback(ctx, input.Responses)
+		scoreLast(err, recorder, &feedback)
+		result.Prompt = feedback.Prompt
+		result.Checks = feedbackChecks(feedback, len(input.Responses))
+	case providers.FeatureResume:
+		var resume []byte
+		resume, err = fs.ReadFile(files, input.File)
+		if err != nil {
+			break
+		}
+		var analysis internal.ResumeAnalyzerResponse
+		analysis, err = generator.ResumeAnalyzer(ctx, resume, input.JobDescription)
+		scoreLast(err, recorder, &analysis)
+		result.Prompt = analysis.Prompt
+		result.Checks = resumeChecks(analysis)
+	case providers.FeatureQuestion:
+		var analysis internal.QuestionAnalysisResponse
+		analysis, err = generator.GenerateQuestionAnalysis(ctx, input.Question)
+		scoreLast(err, recorder, &analysis)
+		result.Prompt = analysis.Prompt
+		result.Checks = questionChecks(analysis)
+	case providers.FeatureModules:
+		var modules internal.ModuleResponse
+		modules, err = generator.GenerateModules(ctx, input.JobRole, input.JobLevel, input.JobDescription, input.Topics)
+		scoreLast(err, recorder, &modules)
+		result.Prompt = modules.Prompt
+		result.Checks = modulesChecks(modules)
+	case providers.FeatureSteps:
+		var steps internal.StepsResponse
+		steps, err = generator.GenerateSteps(ctx, input.ModuleTitle, input.ModuleObjective, input.Topics)
+		scoreLast(err, recorder, &steps)
+		result.Prompt = steps.Prompt
+		result.Checks = stepsChecks(steps)
+	case providers.FeatureLesson:
+		var lesson internal.LessonResponse
+		lesson, err = generator.GenerateLesson(ctx, input.Title, input.Difficulty, input.Topics)
+		scoreLast(err, recorder, &lesson)
+		result.Prompt = lesson.Prompt
+		result.Checks = lessonChecks(lesson)
+	case providers.FeatureTranslate:
+		if input.Exam == nil {
+			err = fmt.Errorf("translate cases need an exam")
+			break
+		}
+		var exam internal.ExamResponse
+		exam, err = generator.TranslateExam(ctx, *input.Exam, input.Type, input.Language)
+		scoreLast(err, recorder, &exam)
+		result.Prompt = exam.Prompt
+		result.Checks = translateChecks(*input.Exam, exam)
+	case providers.FeatureGrade:
+		var grades internal.ShortAnswerGrades
+		grades, err = generator.GradeShortAnswers(ctx, input.Answers)
+		scoreLast(err, recorder, &grades)
+		result.Prompt = grades.Prompt
+		result.Checks = gradeChecks(grades, len(input.Answers))
+	default:
+		err = fmt.Errorf("unknown feature %v", c.Feature)
+	}
+
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	calls := recorder.calls()
+	result.Calls = len(calls)
+	for _, call := range calls {
+		result.PromptTokens += int64(call.PromptTokens)
+		result.CompletionTokens += int64(call.CompletionTokens)
+	}
+	result.Checks = append(result.Checks, jsonChecks(calls))
+
+	result.Passed = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	for _, check := range result.Checks {
+		result.Passed = result.Passed && check.Passed
+	}
+
+	return result
+}
+
+func scoreLast(err error, recorder *recorder, output any) {
+	if err != nil {
+		recorder.decodeLast(output)
+	}
+}
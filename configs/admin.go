@@ -0,0 +1,17 @@
+package configs
+
+import "strings"
+
+// Emails of the users allowed into the /admin routes, from the comma
+// separated ADMIN_EMAILS.
+func GetAdminEmails() []string {
+	var emails []string
+	for _, email := range strings.Split(ProcessEnv("ADMIN_EMAILS"), ",") {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email != "" {
+			emails = append(emails, email)
+		}
+	}
+
+	return emails
+}
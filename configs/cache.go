@@ -0,0 +1,18 @@
+package configs
+
+import (
+	"strings"
+	"time"
+)
+
+// How long the generations of a feature are reused, read from
+// CACHE_<FEATURE>_TTL (e.g. CACHE_QUESTION_TTL=24h). The cache is opt-in,
+// features without a valid positive duration are never cached.
+func GetCacheTTL(feature string) time.Duration {
+	ttl, err := time.ParseDuration(ProcessEnv("CACHE_" + strings.ToUpper(feature) + "_TTL"))
+	if err != nil || ttl < 0 {
+		return 0
+	}
+
+	return ttl
+}
@@ -73,6 +73,13 @@ func InitDatabase() {
 		{"resumes", SetupResumeCollection},
 		{"interviewAttempts", SetupInterviewAttemptCollection},
 		{"examAttempts", SetupExamAttemptCollection},
+		{"learningPaths", SetupLearningPathCollection},
+		{"activities", SetupActivityCollection},
+		{"lessons", SetupLessonCollection},
+		{"jobs", SetupJobCollection},
+		{"usage", SetupUsageCollection},
+		{"flags", SetupFlagCollection},
+		{"generationCache", SetupGenerationCacheCollection},
 	}
 
 	for _, col := range collections {
@@ -218,6 +225,10 @@ func SetupInterviewCollection(ctx context.Context) error {
 					"description": "Interview questions",
 					"bsonType":    "object",
 					"properties": bson.M{
+						"id": bson.M{
+							"bsonType":    "string",
+							"description": "Stable id answers refer to the question by",
+						},
 						"question": bson.M{
 							"bsonType":    "string",
 							"description": "Interview question",
@@ -304,9 +315,9 @@ func SetupExamCollection(ctx context.Context) error {
 				"enum":        []string{"easy", "medium", "hard"},
 				"description": "How easy/hard is the exam",
 			},
-			"exam_type": bson.M{
+			"type": bson.M{
 				"bsonType":    "string",
-				"enum":        []string{"true-false", "multiple-choice"},
+				"enum":        []string{"true-false", "multiple-choice", "multiple-select", "short-answer", "ordering"},
 				"description": "Based on the type the answers will change",
 			},
 			"taken": bson.M{
@@ -327,14 +338,23 @@ func SetupExamCollection(ctx context.Context) error {
 					"description": "Exam questions",
 					"bsonType":    "object",
 					"properties": bson.M{
+						"id": bson.M{
+							"bsonType":    "string",
+							"description": "Stable id answers refer to the question by",
+						},
 						"question": bson.M{
 							"bsonType":    "string",
 							"description": "Exam question",
 						},
+						"type": bson.M{
+							"bsonType":    "string",
+							"enum":        []string{"true-false", "multiple-choice", "multiple-select", "short-answer", "ordering"},
+							"description": "Question type, the one of the exam",
+						},
 						"options": bson.M{
-							"bsonType": "array",
+							"bsonType": []string{"array", "null"},
 							"items": bson.M{
-								"description": "Options to answer question",
+								"description": "Options to answer question, short-answer questions have none",
 								"bsonType":    "string",
 							},
 							"minItems":    1,
@@ -344,6 +364,20 @@ func SetupExamCollection(ctx context.Context) error {
 							"bsonType":    "number",
 							"description": "Question correct answer (index)",
 						},
+						"correctoptions": bson.M{
+							"bsonType":    []string{"array", "null"},
+							"items":       bson.M{"bsonType": "number"},
+							"description": "Correct options (indices) of multiple-select questions",
+						},
+						"correctorder": bson.M{
+							"bsonType":    []string{"array", "null"},
+							"items":       bson.M{"bsonType": "number"},
+							"description": "Options (indices) in the correct order of ordering questions",
+						},
+						"referenceanswer": bson.M{
+							"bsonType":    "string",
+							"description": "Answer short-answer questions are graded against",
+						},
 					},
 				},
 				"minItems":    1,
@@ -600,6 +634,10 @@ func SetupInterviewAttemptCollection(ctx context.Context) error {
 					"description": "Interview questions feedback and answers",
 					"bsonType":    "object",
 					"properties": bson.M{
+						"question_id": bson.M{
+							"bsonType":    "string",
+							"description": "Id of the interview question",
+						},
 						"question": bson.M{
 							"bsonType":    "string",
 							"description": "Interview question",
@@ -705,6 +743,22 @@ func SetupExamAttemptCollection(ctx context.Context) error {
 		return fmt.Errorf("failed to create compoundIndex index: %v", err)
 	}
 
+	// Attempt history of an exam, the latest first
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "exam_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create exam_id/created_at index: %v", err)
+	}
+
+	// Open attempts past their deadline, for the sweeper
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "deadline", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create status/deadline index: %v", err)
+	}
+
 	jsonSchema := bson.M{
 		"bsonType": "object",
 		"required": []string{"user_id", "exam_id"},
@@ -715,13 +769,44 @@ func SetupExamAttemptCollection(ctx context.Context) error {
 					"description": "Exam questions feedback and answers",
 					"bsonType":    "object",
 					"properties": bson.M{
+						"question_id": bson.M{
+							"bsonType":    "string",
+							"description": "Id of the exam question",
+						},
+						"type": bson.M{
+							"bsonType":    "string",
+							"enum":        []string{"true-false", "multiple-choice", "multiple-select", "short-answer", "ordering"},
+							"description": "Type of the exam question",
+						},
+						"correct_options": bson.M{
+							"bsonType":    "array",
+							"items":       bson.M{"bsonType": "number"},
+							"description": "Correct options (indices) of a multiple-select question",
+						},
+						"correct_order": bson.M{
+							"bsonType":    "array",
+							"items":       bson.M{"bsonType": "number"},
+							"description": "Options (indices) in the correct order of an ordering question",
+						},
+						"reference_answer": bson.M{
+							"bsonType":    "string",
+							"description": "Answer a short-answer question was graded against",
+						},
 						"question": bson.M{
 							"bsonType":    "string",
 							"description": "Exam question",
 						},
 						"answer": bson.M{
+							"bsonType":    []string{"number", "array", "string", "null"},
+							"description": "User answer: an index (-1 when unanswered), indices or text depending on the question type",
+						},
+						"credit": bson.M{
 							"bsonType":    "number",
-							"description": "User answer (index)",
+							"description": "Share of the question the answer got right, from 0 to 1",
+						},
+						"feedback": bson.M{
+							"bsonType":    "string",
+							"description": "Why a short answer got its credit",
 						},
 						"correct": bson.M{
 							"bsonType":    "number",
@@ -738,7 +823,7 @@ func SetupExamAttemptCollection(ctx context.Context) error {
 			},
 			"time": bson.M{
 				"bsonType":    "number",
-				"description": "The time that the user took to answer all questions in seconds",
+				"description": "The time that the user took to answer all questions in seconds, measured by the server",
 			},
 			"score": bson.M{
 				"bsonType":    "number",
@@ -748,6 +833,44 @@ func SetupExamAttemptCollection(ctx context.Context) error {
 				"bsonType":    "bool",
 				"description": "Describes if the user passed or not the interview",
 			},
+			"status": bson.M{
+				"bsonType":    "string",
+				"enum":        []string{"in_progress", "submitted", "expired"},
+				"description": "Whether the attempt is being answered, was submitted or ran out of time",
+			},
+			"responses": bson.M{
+				"bsonType":    "object",
+				"description": "Answers saved while the attempt is in progress, keyed by question id",
+			},
+			"revision": bson.M{
+				"bsonType":    "number",
+				"description": "Regenerations of the exam when the attempt started",
+			},
+			"question_ids": bson.M{
+				"bsonType":    "array",
+				"items":       bson.M{"bsonType": "string"},
+				"description": "Questions of the exam when the attempt started, in order",
+			},
+			"current": bson.M{
+				"bsonType":    "number",
+				"description": "Question the user is on, to resume the attempt",
+			},
+			"time_limit": bson.M{
+				"bsonType":    "number",
+				"description": "Seconds the user had to answer, missing for untimed exams",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "When the user started the attempt",
+			},
+			"deadline": bson.M{
+				"bsonType":    "date",
+				"description": "When the time to answer runs out, missing for untimed exams",
+			},
+			"submitted_at": bson.M{
+				"bsonType":    "date",
+				"description": "When the user submitted the attempt, missing while in progress",
+			},
 			"user_id": bson.M{
 				"bsonType":    "objectId",
 				"description": "Reference to user who created the resume analysis",
@@ -784,3 +907,609 @@ func SetupExamAttemptCollection(ctx context.Context) error {
 
 	return nil
 }
+
+func SetupLearningPathCollection(ctx context.Context) error {
+	collection := GetCollection("learningPaths")
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user_id index: %v", err)
+	}
+
+	jsonSchema := bson.M{
+		"bsonType": "object",
+		"required": []string{"job_role", "job_level", "topics", "user_id"},
+		"properties": bson.M{
+			"title": bson.M{
+				"bsonType":    "string",
+				"description": "Learning path descriptive title",
+			},
+			"job_role": bson.M{
+				"bsonType":    "string",
+				"description": "Job the learning path prepares the user for",
+			},
+			"job_level": bson.M{
+				"bsonType":    "string",
+				"enum":        []string{"intership", "junior", "ssr", "senior", "lead"},
+				"description": "Learning path difficulty based on seniority",
+			},
+			"job_description": bson.M{
+				"bsonType":    "string",
+				"description": "Description of the job offer",
+			},
+			"topics": bson.M{
+				"bsonType": "array",
+				"items": bson.M{
+					"description": "List of topics",
+					"bsonType":    "string",
+				},
+				"minItems":    1,
+				"uniqueItems": true,
+			},
+			"pinned": bson.M{
+				"bsonType":    "bool",
+				"description": "Describes if the user pinned to top the learning path",
+			},
+			"percent": bson.M{
+				"bsonType":    "number",
+				"description": "Percentage of the learning path completed by the user",
+			},
+			"completed": bson.M{
+				"bsonType":    "bool",
+				"description": "Describes if the user completed the final challenge of the path",
+			},
+			"modules": bson.M{
+				"bsonType": "array",
+				"items": bson.M{
+					"description": "Learning path modules",
+					"bsonType":    "object",
+					"properties": bson.M{
+						"title": bson.M{
+							"bsonType":    "string",
+							"description": "Module title",
+						},
+						"objective": bson.M{
+							"bsonType":    "string",
+							"description": "What is the aim of the module",
+						},
+						"order": bson.M{
+							"bsonType":    "number",
+							"description": "Position of the module inside the path",
+						},
+						"topic": bson.M{
+							"bsonType":    "string",
+							"description": "Topics included in the module separated by comma",
+						},
+						"steps": bson.M{
+							"bsonType": "array",
+							"items": bson.M{
+								"description": "Module steps",
+								"bsonType":    "object",
+								"properties": bson.M{
+									"title": bson.M{
+										"bsonType":    "string",
+										"description": "Step title",
+									},
+									"type": bson.M{
+										"bsonType":    "string",
+										"description": "Activity type (mock exam, open question, mock interview, lesson, etc)",
+									},
+									"order": bson.M{
+										"bsonType":    "number",
+										"description": "Position of the step inside the module",
+									},
+									"difficulty": bson.M{
+										"bsonType":    "string",
+										"description": "How easy/hard is the step",
+									},
+									"activity_id": bson.M{
+										"bsonType":    "objectId",
+										"description": "Reference to the activity that carries out the step",
+									},
+								},
+							},
+						},
+					},
+				},
+				"minItems": 1,
+			},
+			"user_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to user who created the learning path",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: "learningPaths"},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+
+	err = DB.Database("PrepAi").RunCommand(ctx, command).Err()
+	if err != nil {
+		if strings.Contains(err.Error(), "namespace") {
+			createOpts := options.CreateCollection().SetValidator(validator)
+			err = DB.Database("PrepAi").CreateCollection(ctx, "learningPaths", createOpts)
+			if err != nil {
+				return fmt.Errorf("failed to create learningPaths collection: %v", err)
+			}
+		} else {
+			return fmt.Errorf("failed to set up validator: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func SetupActivityCollection(ctx context.Context) error {
+	collection := GetCollection("activities")
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "path_id", Value: 1}, {Key: "module_order", Value: 1}, {Key: "step_order", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user_id/path_id index: %v", err)
+	}
+
+	jsonSchema := bson.M{
+		"bsonType": "object",
+		"required": []string{"content_type", "status", "user_id"},
+		"properties": bson.M{
+			"title": bson.M{
+				"bsonType":    "string",
+				"description": "Activity descriptive title",
+			},
+			"content_type": bson.M{
+				"bsonType":    "string",
+				"enum":        []string{"exam", "interview", "question", "lesson"},
+				"description": "Kind of content that carries out the activity",
+			},
+			"content_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to the exam, interview, question or lesson of the activity",
+			},
+			"status": bson.M{
+				"bsonType":    "string",
+				"enum":        []string{"locked", "available", "in_progress", "completed"},
+				"description": "User progress on the activity",
+			},
+			"difficulty": bson.M{
+				"bsonType":    "string",
+				"description": "How easy/hard is the activity",
+			},
+			"path_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to the learning path, empty for standalone practice",
+			},
+			"module_order": bson.M{
+				"bsonType":    "number",
+				"description": "Order of the module the activity belongs to",
+			},
+			"step_order": bson.M{
+				"bsonType":    "number",
+				"description": "Order of the step inside its module",
+			},
+			"user_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to user who owns the activity",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: "activities"},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+
+	err = DB.Database("PrepAi").RunCommand(ctx, command).Err()
+	if err != nil {
+		if strings.Contains(err.Error(), "namespace") {
+			createOpts := options.CreateCollection().SetValidator(validator)
+			err = DB.Database("PrepAi").CreateCollection(ctx, "activities", createOpts)
+			if err != nil {
+				return fmt.Errorf("failed to create activities collection: %v", err)
+			}
+		} else {
+			return fmt.Errorf("failed to set up validator: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func SetupLessonCollection(ctx context.Context) error {
+	collection := GetCollection("lessons")
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "activity_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user_id/activity_id index: %v", err)
+	}
+
+	jsonSchema := bson.M{
+		"bsonType": "object",
+		"required": []string{"title", "user_id"},
+		"properties": bson.M{
+			"title": bson.M{
+				"bsonType":    "string",
+				"description": "Lesson descriptive title",
+			},
+			"difficulty": bson.M{
+				"bsonType":    "string",
+				"description": "How easy/hard is the lesson",
+			},
+			"summary": bson.M{
+				"bsonType":    "string",
+				"description": "What the user will learn in the lesson",
+			},
+			"sections": bson.M{
+				"bsonType": "array",
+				"items": bson.M{
+					"description": "Lesson sections",
+					"bsonType":    "object",
+					"properties": bson.M{
+						"heading": bson.M{
+							"bsonType":    "string",
+							"description": "Section title",
+						},
+						"content": bson.M{
+							"bsonType":    "string",
+							"description": "Section explanation",
+						},
+						"example": bson.M{
+							"bsonType":    "string",
+							"description": "Example that illustrates the section",
+						},
+					},
+				},
+				"minItems": 1,
+			},
+			"key_takeaways": bson.M{
+				"bsonType": "array",
+				"items": bson.M{
+					"description": "What the user should remember from the lesson",
+					"bsonType":    "string",
+				},
+			},
+			"user_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to user who owns the lesson",
+			},
+			"activity_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to activity for which this lesson belongs to",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: "lessons"},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+
+	err = DB.Database("PrepAi").RunCommand(ctx, command).Err()
+	if err != nil {
+		if strings.Contains(err.Error(), "namespace") {
+			createOpts := options.CreateCollection().SetValidator(validator)
+			err = DB.Database("PrepAi").CreateCollection(ctx, "lessons", createOpts)
+			if err != nil {
+				return fmt.Errorf("failed to create lessons collection: %v", err)
+			}
+		} else {
+			return fmt.Errorf("failed to set up validator: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func SetupJobCollection(ctx context.Context) error {
+	collection := GetCollection("jobs")
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			// Finished jobs are only polled for a short while
+			Keys:    bson.D{{Key: "completed_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create jobs indexes: %v", err)
+	}
+
+	jsonSchema := bson.M{
+		"bsonType": "object",
+		"required": []string{"type", "status", "user_id"},
+		"properties": bson.M{
+			"type": bson.M{
+				"bsonType":    "string",
+				"description": "What the job generates",
+			},
+			"status": bson.M{
+				"bsonType":    "string",
+				"enum":        []string{"queued", "running", "completed", "failed"},
+				"description": "Job progress",
+			},
+			"attempts": bson.M{
+				"bsonType":    "number",
+				"description": "How many times a worker picked the job",
+			},
+			"error": bson.M{
+				"bsonType":    "string",
+				"description": "Why the job failed",
+			},
+			"result_type": bson.M{
+				"bsonType":    "string",
+				"description": "Kind of document produced by the job",
+			},
+			"result_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to the document produced by the job",
+			},
+			"user_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to user who requested the job",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: "jobs"},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+
+	err = DB.Database("PrepAi").RunCommand(ctx, command).Err()
+	if err != nil {
+		if strings.Contains(err.Error(), "namespace") {
+			createOpts := options.CreateCollection().SetValidator(validator)
+			err = DB.Database("PrepAi").CreateCollection(ctx, "jobs", createOpts)
+			if err != nil {
+				return fmt.Errorf("failed to create jobs collection: %v", err)
+			}
+		} else {
+			return fmt.Errorf("failed to set up validator: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func SetupUsageCollection(ctx context.Context) error {
+	collection := GetCollection("usage")
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create usage index: %v", err)
+	}
+
+	jsonSchema := bson.M{
+		"bsonType": "object",
+		"required": []string{"model", "feature", "prompt_tokens", "completion_tokens", "user_id", "created_at"},
+		"properties": bson.M{
+			"model": bson.M{
+				"bsonType":    "string",
+				"description": "Model that answered the call",
+			},
+			"feature": bson.M{
+				"bsonType":    "string",
+				"description": "What was generated (exam, interview, feedback...)",
+			},
+			"prompt_tokens": bson.M{
+				"bsonType":    "number",
+				"minimum":     0,
+				"description": "Tokens sent to the model",
+			},
+			"completion_tokens": bson.M{
+				"bsonType":    "number",
+				"minimum":     0,
+				"description": "Tokens generated by the model",
+			},
+			"user_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to user billed for the call",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "When the call was made",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: "usage"},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+
+	err = DB.Database("PrepAi").RunCommand(ctx, command).Err()
+	if err != nil {
+		if strings.Contains(err.Error(), "namespace") {
+			createOpts := options.CreateCollection().SetValidator(validator)
+			err = DB.Database("PrepAi").CreateCollection(ctx, "usage", createOpts)
+			if err != nil {
+				return fmt.Errorf("failed to create usage collection: %v", err)
+			}
+		} else {
+			return fmt.Errorf("failed to set up validator: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func SetupFlagCollection(ctx context.Context) error {
+	collection := GetCollection("flags")
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// A user can flag each document once
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "content_type", Value: 1}, {Key: "content_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "prompt.experiment", Value: 1}, {Key: "prompt.version", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create flags indexes: %v", err)
+	}
+
+	jsonSchema := bson.M{
+		"bsonType": "object",
+		"required": []string{"content_type", "content_id", "user_id", "created_at"},
+		"properties": bson.M{
+			"content_type": bson.M{
+				"bsonType":    "string",
+				"enum":        []string{"exam", "interview", "feedback"},
+				"description": "Kind of document flagged",
+			},
+			"content_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to the flagged document",
+			},
+			"reason": bson.M{
+				"bsonType":    "string",
+				"description": "Why the user flagged it",
+			},
+			"user_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Reference to user who flagged the document",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "When the document was flagged",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: "flags"},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+
+	err = DB.Database("PrepAi").RunCommand(ctx, command).Err()
+	if err != nil {
+		if strings.Contains(err.Error(), "namespace") {
+			createOpts := options.CreateCollection().SetValidator(validator)
+			err = DB.Database("PrepAi").CreateCollection(ctx, "flags", createOpts)
+			if err != nil {
+				return fmt.Errorf("failed to create flags collection: %v", err)
+			}
+		} else {
+			return fmt.Errorf("failed to set up validator: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func SetupGenerationCacheCollection(ctx context.Context) error {
+	collection := GetCollection("generationCache")
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// Entries are removed once they expire
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create generationCache index: %v", err)
+	}
+
+	jsonSchema := bson.M{
+		"bsonType": "object",
+		"required": []string{"feature", "text", "created_at", "expires_at"},
+		"properties": bson.M{
+			"feature": bson.M{
+				"bsonType":    "string",
+				"description": "What was generated (exam, question...)",
+			},
+			"text": bson.M{
+				"bsonType":    "string",
+				"description": "Validated response of the model",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "When the response was generated",
+			},
+			"expires_at": bson.M{
+				"bsonType":    "date",
+				"description": "When the response stops being reused",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: "generationCache"},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+
+	err = DB.Database("PrepAi").RunCommand(ctx, command).Err()
+	if err != nil {
+		if strings.Contains(err.Error(), "namespace") {
+			createOpts := options.CreateCollection().SetValidator(validator)
+			err = DB.Database("PrepAi").CreateCollection(ctx, "generationCache", createOpts)
+			if err != nil {
+				return fmt.Errorf("failed to create generationCache collection: %v", err)
+			}
+		} else {
+			return fmt.Errorf("failed to set up validator: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package configs
+
+import "time"
+
+// How long exam attempts may take. Exams without a time limit of their own
+// get PerQuestion for each question their difficulty asks for. Submissions
+// are still accepted up to Grace after the deadline to make up for slow
+// networks, and every SweepInterval the attempts left open past that are
+// closed.
+type ExamTimingConfig struct {
+	PerQuestion   time.Duration
+	Grace         time.Duration
+	SweepInterval time.Duration
+}
+
+func GetExamTimingConfig() ExamTimingConfig {
+	return ExamTimingConfig{
+		PerQuestion:   parseDuration(ProcessEnv("EXAM_TIME_PER_QUESTION"), 90*time.Second),
+		Grace:         parseDuration(ProcessEnv("EXAM_SUBMIT_GRACE"), 30*time.Second),
+		SweepInterval: parseDuration(ProcessEnv("EXAM_SWEEP_INTERVAL"), time.Minute),
+	}
+}
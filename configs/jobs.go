@@ -0,0 +1,13 @@
+package configs
+
+import "strconv"
+
+// How many generation jobs run at the same time in this process.
+func GetJobWorkers() int {
+	value, err := strconv.Atoi(ProcessEnv("JOB_WORKERS"))
+	if err != nil || value < 1 {
+		return 4
+	}
+
+	return value
+}
@@ -0,0 +1,184 @@
+package configs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BackendGemini = "gemini"
+	BackendOpenAI = "openai"
+	BackendOllama = "ollama"
+)
+
+// LLMConfig describes which backend and model a feature generates with.
+type LLMConfig struct {
+	Backend string
+	BaseURL string
+	APIKey  string
+	Model   string
+	// Same backend and settings with another model, used while Model is
+	// unavailable. Empty means there is no fallback.
+	FallbackModel   string
+	Temperature     *float32
+	MaxOutputTokens *int32
+}
+
+// Reads the LLM_* variables, a feature can override any of them with
+// LLM_<FEATURE>_* (e.g. LLM_RESUME_BACKEND=openai).
+func GetLLMConfig(feature string) LLMConfig {
+	env := func(key string) string {
+		if feature != "" {
+			value := ProcessEnv("LLM_" + strings.ToUpper(feature) + "_" + key)
+			if value != "" {
+				return value
+			}
+		}
+		return ProcessEnv("LLM_" + key)
+	}
+
+	config := LLMConfig{
+		Backend: strings.ToLower(env("BACKEND")),
+		BaseURL: env("BASE_URL"),
+		APIKey:  env("API_KEY"),
+		Model:   env("MODEL"),
+
+		FallbackModel: env("FALLBACK_MODEL"),
+	}
+
+	if config.Backend == "" {
+		config.Backend = BackendGemini
+	}
+
+	switch config.Backend {
+	case BackendOpenAI:
+		if config.BaseURL == "" {
+			config.BaseURL = "https://api.openai.com/v1"
+		}
+		if config.Model == "" {
+			config.Model = "gpt-4o-mini"
+		}
+	case BackendOllama:
+		if config.BaseURL == "" {
+			config.BaseURL = "http://localhost:11434"
+		}
+		if config.Model == "" {
+			config.Model = "llama3.1"
+		}
+	}
+
+	if value, err := strconv.ParseFloat(env("TEMPERATURE"), 32); err == nil {
+		temp := float32(value)
+		config.Temperature = &temp
+	} else {
+		temp := float32(0.5)
+		config.Temperature = &temp
+	}
+
+	if value, err := strconv.ParseInt(env("MAX_OUTPUT_TOKENS"), 10, 32); err == nil {
+		maxOutputTokens := int32(value)
+		config.MaxOutputTokens = &maxOutputTokens
+	} else {
+		maxOutputTokens := int32(8192)
+		config.MaxOutputTokens = &maxOutputTokens
+	}
+
+	return config
+}
+
+// How many times a generator asks the model to repair an invalid response.
+func GetMaxRepairs() int {
+	value, err := strconv.Atoi(ProcessEnv("LLM_MAX_REPAIRS"))
+	if err != nil || value < 0 {
+		return 2
+	}
+
+	return value
+}
+
+// How calls to the model are bounded and retried, shared by every feature.
+type ResilienceConfig struct {
+	// Deadline of a single call, retries get their own
+	Timeout time.Duration
+	// Retries of a call failing with a transient error
+	MaxRetries int
+	// Backoff before the first retry, doubled on each one up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Consecutive failures that open the circuit of a model, and how long it
+	// stays open before a call is let through again
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+func GetResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Timeout:          parseDuration(ProcessEnv("LLM_TIMEOUT"), 90*time.Second),
+		MaxRetries:       parseCount(ProcessEnv("LLM_MAX_RETRIES"), 2),
+		BaseDelay:        parseDuration(ProcessEnv("LLM_RETRY_BASE_DELAY"), 500*time.Millisecond),
+		MaxDelay:         parseDuration(ProcessEnv("LLM_RETRY_MAX_DELAY"), 8*time.Second),
+		BreakerThreshold: parseCount(ProcessEnv("LLM_BREAKER_THRESHOLD"), 5),
+		BreakerCooldown:  parseDuration(ProcessEnv("LLM_BREAKER_COOLDOWN"), 30*time.Second),
+	}
+}
+
+// Exams with more questions than this are generated in concurrent chunks,
+// zero disables chunking.
+func GetExamChunkSize() int {
+	return parseCount(ProcessEnv("EXAM_CHUNK_SIZE"), 15)
+}
+
+// How much work each model is given at once. Every call weighs 1 plus
+// FileWeight per attached file, a model runs calls up to Concurrency in
+// total weight and the rest wait in line for at most QueueTimeout.
+type LimiterConfig struct {
+	Concurrency  int64
+	FileWeight   int64
+	QueueTimeout time.Duration
+}
+
+func GetLimiterConfig() LimiterConfig {
+	return LimiterConfig{
+		Concurrency:  int64(parseCount(ProcessEnv("LLM_CONCURRENCY"), 8)),
+		FileWeight:   int64(parseCount(ProcessEnv("LLM_FILE_WEIGHT"), 4)),
+		QueueTimeout: parseDuration(ProcessEnv("LLM_QUEUE_TIMEOUT"), 30*time.Second),
+	}
+}
+
+// Where model interactions are recorded or replayed from, an empty Dir
+// disables it. Mode is record (default), replay or auto.
+type CassetteConfig struct {
+	Dir  string
+	Mode string
+}
+
+func GetCassetteConfig() CassetteConfig {
+	config := CassetteConfig{
+		Dir:  ProcessEnv("LLM_CASSETTE_DIR"),
+		Mode: strings.ToLower(ProcessEnv("LLM_CASSETTE_MODE")),
+	}
+	if config.Mode == "" {
+		config.Mode = "record"
+	}
+
+	return config
+}
+
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+
+	return duration
+}
+
+func parseCount(value string, fallback int) int {
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return fallback
+	}
+
+	return count
+}
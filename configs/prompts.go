@@ -0,0 +1,21 @@
+package configs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Directory with prompt templates that replace or add to the embedded ones.
+func GetPromptsDir() string {
+	return ProcessEnv("PROMPTS_DIR")
+}
+
+// Version pinned with PROMPT_<NAME>_VERSION, zero when the newest should be used.
+func GetPromptVersion(name string) int {
+	version, err := strconv.Atoi(ProcessEnv("PROMPT_" + strings.ToUpper(name) + "_VERSION"))
+	if err != nil || version < 1 {
+		return 0
+	}
+
+	return version
+}
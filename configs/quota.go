@@ -0,0 +1,26 @@
+package configs
+
+import "strconv"
+
+// Token limits per user, counting prompt and completion tokens. Zero means
+// there is no limit.
+type QuotaConfig struct {
+	DailyTokens   int64
+	MonthlyTokens int64
+}
+
+func GetQuotaConfig() QuotaConfig {
+	return QuotaConfig{
+		DailyTokens:   parseTokens(ProcessEnv("QUOTA_DAILY_TOKENS")),
+		MonthlyTokens: parseTokens(ProcessEnv("QUOTA_MONTHLY_TOKENS")),
+	}
+}
+
+func parseTokens(value string) int64 {
+	tokens, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || tokens < 0 {
+		return 0
+	}
+
+	return tokens
+}
@@ -0,0 +1,527 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/internal"
+	"prepai.app/models"
+)
+
+func GetActivities(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	activities, err := models.GetAllUserActivities(userId, context.Query("status"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch activities. Try again later."})
+		return
+	}
+
+	context.JSON(http.StatusOK, activities)
+}
+
+func GetActivity(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	activityId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid activity ID format",
+		})
+		return
+	}
+
+	activity, err := models.GetActivityById(activityId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch activity. Try again later."})
+		return
+	}
+
+	if activity.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Activity does not belong to you",
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, activity)
+}
+
+func GetActivityContent(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	activityId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid activity ID format",
+		})
+		return
+	}
+
+	activity, err := models.GetActivityById(activityId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch activity. Try again later."})
+		return
+	}
+
+	if activity.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Activity does not belong to you",
+		})
+		return
+	}
+
+	if activity.Status == models.ActivityLocked {
+		context.JSON(http.StatusForbidden, gin.H{
+			"message": "Activity is locked",
+		})
+		return
+	}
+
+	// Steps get their content generated the first time they are opened
+	if activity.ContentId.IsZero() {
+		err = materializeActivity(internal.WithUserId(context.Request.Context(), userId), activity)
+		if err != nil {
+			context.JSON(generationErrorStatus(err), gin.H{
+				"message": "Could not generate activity content: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	content, _, err := getActivityContent(activity.ContentType, activity.ContentId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Could not fetch activity content",
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"content_type": activity.ContentType,
+		"data":         content,
+	})
+}
+
+func CreateActivity(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var activity models.Activity
+	err = context.ShouldBindJSON(&activity)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not parse request data.",
+		})
+		return
+	}
+
+	if activity.ContentType == "" || activity.ContentId.IsZero() {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Missing either content type or content id",
+		})
+		return
+	}
+
+	content, ownerId, err := getActivityContent(activity.ContentType, activity.ContentId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not fetch activity content",
+		})
+		return
+	}
+
+	if ownerId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Content does not belong to you",
+		})
+		return
+	}
+
+	// Standalone practice items are never part of a path, so they start unlocked
+	activity.Status = models.ActivityAvailable
+	activity.PathId = bson.NilObjectID
+	activity.ModuleOrder = 0
+	activity.StepOrder = 0
+	activity.UserId = userId
+
+	err = activity.Save()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	err = linkContentToActivity(content, activity.Id)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "Activity created successfully",
+		"data":    activity,
+	})
+}
+
+// Completes a lesson or open question step once the user went through it,
+// exams and interviews are completed when they are submitted.
+func CompleteActivity(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	activityId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid activity ID format",
+		})
+		return
+	}
+
+	activity, err := models.GetActivityById(activityId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch activity. Try again later."})
+		return
+	}
+
+	if activity.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Activity does not belong to you",
+		})
+		return
+	}
+
+	if !activity.CompletedByUser() {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Exams and interviews are completed by submitting them",
+		})
+		return
+	}
+
+	if activity.Status == models.ActivityLocked {
+		context.JSON(http.StatusForbidden, gin.H{
+			"message": "Activity is locked",
+		})
+		return
+	}
+
+	if activity.ContentId.IsZero() {
+		context.JSON(http.StatusConflict, gin.H{
+			"message": "Open the activity before completing it",
+		})
+		return
+	}
+
+	err = activity.Complete()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Could not complete activity. Try again later.",
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Activity completed successfully",
+		"data":    activity,
+	})
+}
+
+func DeleteActivity(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	activityId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid activity ID format",
+		})
+		return
+	}
+
+	activity, err := models.GetActivityById(activityId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch activity. Try again later."})
+		return
+	}
+
+	if activity.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Activity does not belong to you",
+		})
+		return
+	}
+
+	if !activity.PathId.IsZero() {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Learning path activities are deleted with their path",
+		})
+		return
+	}
+
+	err = activity.Delete()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Activity deleted successfully",
+	})
+}
+
+// Loads the document backing an activity and returns it alongside its owner.
+func getActivityContent(contentType string, contentId bson.ObjectID) (any, bson.ObjectID, error) {
+	switch contentType {
+	case models.ContentExam:
+		exam, err := models.GetExamById(contentId, false)
+		if err != nil {
+			return nil, bson.NilObjectID, err
+		}
+		return exam, exam.UserId, nil
+	case models.ContentInterview:
+		interview, err := models.GetInterviewById(contentId)
+		if err != nil {
+			return nil, bson.NilObjectID, err
+		}
+		return interview, interview.UserId, nil
+	case models.ContentQuestion:
+		question, err := models.GetQuestionById(contentId)
+		if err != nil {
+			return nil, bson.NilObjectID, err
+		}
+		return question, question.UserId, nil
+	case models.ContentLesson:
+		lesson, err := models.GetLessonById(contentId)
+		if err != nil {
+			return nil, bson.NilObjectID, err
+		}
+		return lesson, lesson.UserId, nil
+	default:
+		return nil, bson.NilObjectID, errors.New("unsupported content type")
+	}
+}
+
+func linkContentToActivity(content any, activityId bson.ObjectID) error {
+	switch content := content.(type) {
+	case *models.Exam:
+		return content.SetActivity(activityId)
+	case *models.Interview:
+		return content.SetActivity(activityId)
+	default:
+		return nil
+	}
+}
+
+// Generates the exam, interview, question or lesson that carries out a
+// learning path step and links it to the activity.
+func materializeActivity(ctx context.Context, activity *models.Activity) error {
+	if activity.PathId.IsZero() {
+		return errors.New("activity has no content")
+	}
+
+	path, err := models.GetLearningPathById(activity.PathId)
+	if err != nil {
+		return err
+	}
+
+	module := path.GetModule(activity.ModuleOrder)
+	if module == nil {
+		return errors.New("activity module not found")
+	}
+
+	// Content is written in the language the path was created in
+	if path.Language != "" {
+		ctx = internal.WithLanguage(ctx, path.Language)
+	}
+	language := internal.LanguageFromContext(ctx)
+
+	topics := splitTopics(module.Topic)
+	difficulty := normalizeDifficulty(activity.Difficulty)
+
+	var contentId bson.ObjectID
+	var discard func() error
+
+	switch activity.ContentType {
+	case models.ContentExam:
+		subject := fmt.Sprintf("%v (%v)", activity.Title, strings.Join(topics, ", "))
+		result, err := Generator.GenerateExam(ctx, subject, difficulty, internal.MultipleChoice)
+		if err != nil {
+			return err
+		}
+
+		exam := models.Exam{
+			Title:            result.Title,
+			Subject:          activity.Title,
+			Difficulty:       difficulty,
+			Type:             internal.MultipleChoice,
+			Language:         language,
+			Questions:        result.Questions,
+			Prompt:           &result.Prompt,
+			ShuffleSeed:      result.Seed,
+			CorrectPositions: result.CorrectPositions(),
+			UserId:           activity.UserId,
+			ActividyId:       activity.Id,
+		}
+		err = exam.Save()
+		if err != nil {
+			return err
+		}
+		contentId, discard = exam.Id, exam.Delete
+
+	case models.ContentInterview:
+		interviewTopics := append(topics, activity.Title)
+		result, err := Generator.GenerateInterview(ctx, path.JobRole, path.JobLevel, interviewTopics)
+		if err != nil {
+			return err
+		}
+
+		interview := models.Interview{
+			Title:      result.Title,
+			JobRole:    path.JobRole,
+			JobLevel:   path.JobLevel,
+			Topics:     interviewTopics,
+			Language:   language,
+			Questions:  result.Questions,
+			Prompt:     &result.Prompt,
+			UserId:     activity.UserId,
+			ActividyId: activity.Id,
+		}
+		err = interview.Save()
+		if err != nil {
+			return err
+		}
+		contentId, discard = interview.Id, interview.Delete
+
+	case models.ContentQuestion:
+		result, err := Generator.GenerateQuestionAnalysis(ctx, activity.Title)
+		if err != nil {
+			return err
+		}
+
+		question := models.Question{
+			Question:       activity.Title,
+			Type:           result.Type,
+			Difficulty:     result.Difficulty,
+			Explanation:    result.Explanation,
+			ExpectedLength: result.ExpectedLength,
+			IdealAnswer:    result.IdealAnswer,
+			Language:       language,
+			UserId:         activity.UserId,
+		}
+		err = question.Save()
+		if err != nil {
+			return err
+		}
+		contentId, discard = question.Id, question.Delete
+
+	default:
+		result, err := Generator.GenerateLesson(ctx, activity.Title, difficulty, topics)
+		if err != nil {
+			return err
+		}
+
+		lesson := models.Lesson{
+			Title:        activity.Title,
+			Difficulty:   difficulty,
+			Summary:      result.Summary,
+			Sections:     result.Sections,
+			KeyTakeaways: result.KeyTakeaways,
+			Language:     language,
+			UserId:       activity.UserId,
+			ActivityId:   activity.Id,
+		}
+		err = lesson.Save()
+		if err != nil {
+			return err
+		}
+		contentId, discard = lesson.Id, lesson.Delete
+	}
+
+	linked, err := activity.SetContent(contentId)
+	if err != nil {
+		discard()
+		return err
+	}
+
+	// Another request generated the content first, keep that one
+	if !linked {
+		discard()
+
+		current, err := models.GetActivityById(activity.Id)
+		if err != nil {
+			return err
+		}
+		activity.ContentId = current.ContentId
+	}
+
+	return nil
+}
+
+func splitTopics(topic string) []string {
+	var topics []string
+	for _, t := range strings.Split(topic, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			topics = append(topics, t)
+		}
+	}
+
+	return topics
+}
+
+// Step difficulties are free text, exams only accept easy, medium or hard.
+func normalizeDifficulty(difficulty string) string {
+	difficulty = strings.ToLower(difficulty)
+
+	switch {
+	case strings.Contains(difficulty, "hard"), strings.Contains(difficulty, "advanced"):
+		return "hard"
+	case strings.Contains(difficulty, "medium"), strings.Contains(difficulty, "intermediate"):
+		return "medium"
+	default:
+		return "easy"
+	}
+}
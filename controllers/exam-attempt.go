@@ -1,14 +1,18 @@
 package controllers
 
 import (
-	"math"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/configs"
+	"prepai.app/internal"
 	"prepai.app/models"
 )
 
+// Returns the attempt, the latest one unless the route names it. Attempts in
+// progress carry the answers saved so far so the client can resume them.
 func GetExamAttempt(context *gin.Context) {
 	userId, err := GetUserId(context)
 	if err != nil {
@@ -21,27 +25,66 @@ func GetExamAttempt(context *gin.Context) {
 	examId, err := bson.ObjectIDFromHex(context.Param("id"))
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"message": "Invalid interview ID format",
+			"message": "Invalid exam ID format",
+		})
+		return
+	}
+
+	examAttempt, ok := findExamAttempt(context, examId, userId)
+	if !ok {
+		return
+	}
+
+	examAttempt.SetRemaining(time.Now())
+	context.JSON(http.StatusOK, examAttempt)
+}
+
+// Lists every attempt of the exam with its score, time and result, the
+// latest first.
+func GetExamAttempts(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
 		})
 		return
 	}
 
-	examAttempt, err := models.GetAttemptByExamId(examId)
+	examId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid exam ID format",
+		})
+		return
+	}
+
+	exam, err := models.GetExamById(examId, false)
 	if err != nil {
 		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "Could not fetch exam attempt",
+			"message": "Could not fetch exam",
 		})
 		return
 	}
 
-	if examAttempt.UserId != userId {
+	if exam.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Exam does not belong to you",
+		})
+		return
+	}
+
+	attempts, err := models.GetExamAttempts(examId)
+	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Exam attempt does not belong to you",
+			"message": "Could not fetch exam attempts",
 		})
 		return
 	}
 
-	context.JSON(http.StatusOK, examAttempt)
+	context.JSON(http.StatusOK, gin.H{
+		"attempts": attempts,
+		"scores":   exam.Scores,
+	})
 }
 
 func CreateExamAttempt(context *gin.Context) {
@@ -61,9 +104,45 @@ func CreateExamAttempt(context *gin.Context) {
 		return
 	}
 
-	var examAttempt models.ExamAttempt
-	examAttempt.UserId = userId
-	examAttempt.ExamId = examId
+	exam, err := models.GetExamById(examId, false)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not fetch exam",
+		})
+		return
+	}
+
+	if exam.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Exam does not belong to you",
+		})
+		return
+	}
+
+	err = startActivity(exam.ActividyId, userId)
+	if err != nil {
+		if err == errActivityLocked {
+			context.JSON(http.StatusForbidden, gin.H{
+				"message": "This exam belongs to a locked step",
+			})
+			return
+		}
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to start activity: " + err.Error(),
+		})
+		return
+	}
+
+	err = exam.AssignQuestionIds()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to assign question ids: " + err.Error(),
+		})
+		return
+	}
+
+	duration := exam.AttemptDuration(configs.GetExamTimingConfig().PerQuestion)
+	examAttempt := models.NewExamAttempt(exam, userId, duration)
 
 	err = examAttempt.Save()
 	if err != nil {
@@ -75,15 +154,28 @@ func CreateExamAttempt(context *gin.Context) {
 
 	context.JSON(http.StatusCreated, gin.H{
 		"message": "Exam attempt created successfully",
+		"data":    examAttempt,
 	})
 }
 
+// The time taken is measured on the server from the start of the attempt.
+// Answers are keyed by question id and take over the saved answers, each in
+// the form its question type takes. Responses by question position are
+// still read from older clients.
 type ExamSubmission struct {
-	Responses []int64
-	Time      int64
+	Answers   map[string]internal.Answer `json:"answers"`
+	Responses []internal.Answer          `json:"responses"`
 }
 
-func SubmitExamAttempt(context *gin.Context) {
+// Answers to save keyed by question id, null or -1 clears one. Current is
+// the position of the question the user is on.
+type ExamProgress struct {
+	Answers map[string]internal.Answer `json:"answers"`
+	Current *int64                     `json:"current"`
+}
+
+// Saves answers of an attempt in progress so it can be resumed later.
+func SaveExamAttemptAnswers(context *gin.Context) {
 	userId, err := GetUserId(context)
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -95,13 +187,13 @@ func SubmitExamAttempt(context *gin.Context) {
 	examId, err := bson.ObjectIDFromHex(context.Param("id"))
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"message": "Invalid interview ID format",
+			"message": "Invalid exam ID format",
 		})
 		return
 	}
 
-	var userResponse ExamSubmission
-	err = context.ShouldBindJSON(&userResponse)
+	var progress ExamProgress
+	err = context.ShouldBindJSON(&progress)
 	if err != nil {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "Could not parse request data.",
@@ -109,9 +201,106 @@ func SubmitExamAttempt(context *gin.Context) {
 		return
 	}
 
-	if len(userResponse.Responses) == 0 {
+	exam, err := models.GetExamById(examId, true)
+	if err != nil {
 		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "Responses array cannot be empty",
+			"message": "Could not fetch exam",
+		})
+		return
+	}
+
+	if exam.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Exam does not belong to you",
+		})
+		return
+	}
+
+	examAttempt, ok := findExamAttempt(context, examId, userId)
+	if !ok {
+		return
+	}
+
+	if !examAttempt.Open() {
+		context.JSON(http.StatusConflict, gin.H{
+			"message": "Exam attempt is not in progress",
+		})
+		return
+	}
+
+	if !checkExamRevision(context, exam, examAttempt) {
+		return
+	}
+
+	now := time.Now()
+	if examAttempt.Overdue(now, configs.GetExamTimingConfig().Grace) {
+		closeExamAttempt(context, exam, examAttempt, examAttempt.Responses, models.AttemptExpired, now)
+		return
+	}
+
+	if !checkExamAnswers(context, exam, progress.Answers) {
+		return
+	}
+
+	if progress.Current != nil && (*progress.Current < 0 || *progress.Current >= int64(len(exam.Questions))) {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Current question out of range",
+		})
+		return
+	}
+
+	saved, err := examAttempt.SaveResponses(progress.Answers, progress.Current)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to save answers: " + err.Error(),
+		})
+		return
+	}
+
+	if !saved {
+		context.JSON(http.StatusConflict, gin.H{
+			"message": "Exam attempt is not in progress",
+		})
+		return
+	}
+
+	examAttempt, err = models.GetExamAttemptById(examAttempt.Id)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Could not fetch exam attempt",
+		})
+		return
+	}
+
+	examAttempt.SetRemaining(now)
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Answers saved successfully",
+		"data":    examAttempt,
+	})
+}
+
+func SubmitExamAttempt(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	examId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid interview ID format",
+		})
+		return
+	}
+
+	var userResponse ExamSubmission
+	err = context.ShouldBindJSON(&userResponse)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not parse request data.",
 		})
 		return
 	}
@@ -131,43 +320,81 @@ func SubmitExamAttempt(context *gin.Context) {
 		return
 	}
 
-	answers := make([]models.ExamAnswer, len(userResponse.Responses))
-	totalScore := 0.0
+	examAttempt, ok := findExamAttempt(context, examId, userId)
+	if !ok {
+		return
+	}
+
+	if !examAttempt.Open() {
+		context.JSON(http.StatusConflict, gin.H{
+			"message": "Exam attempt was already submitted",
+		})
+		return
+	}
+
+	if len(userResponse.Answers) == 0 && len(userResponse.Responses) == 0 && len(examAttempt.Responses) == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Responses array cannot be empty",
+		})
+		return
+	}
 
-	for i, response := range userResponse.Responses {
-		if response == exam.Questions[i].Correct {
-			totalScore++
-		}
+	if !checkExamRevision(context, exam, examAttempt) {
+		return
+	}
 
-		answers[i] = models.ExamAnswer{
-			Question:    exam.Questions[i].Question,
-			Answer:      response,
-			Correct:     exam.Questions[i].Correct,
-			Explanation: exam.Questions[i].Explanation,
-		}
+	now := time.Now()
+
+	// Answers sent once the time is up do not count, the saved ones do
+	if examAttempt.Overdue(now, configs.GetExamTimingConfig().Grace) {
+		closeExamAttempt(context, exam, examAttempt, examAttempt.Responses, models.AttemptExpired, now)
+		return
 	}
 
-	examAttempt, err := models.GetAttemptByExamId(examId)
-	if err != nil {
+	if len(userResponse.Responses) > len(exam.Questions) {
 		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "Could not fetch exam attempt",
+			"message": "There are more responses than questions",
 		})
 		return
 	}
 
-	totalQuestions := float64(len(answers))
+	answers := make(map[string]internal.Answer, len(userResponse.Answers)+len(userResponse.Responses))
+	for i, response := range userResponse.Responses {
+		answers[exam.Questions[i].Id] = response
+	}
+	for id, answer := range userResponse.Answers {
+		answers[id] = answer
+	}
+
+	if !checkExamAnswers(context, exam, answers) {
+		return
+	}
+
+	responses := make(map[string]internal.Answer, len(examAttempt.Responses)+len(answers))
+	for id, response := range examAttempt.Responses {
+		responses[id] = response
+	}
+	for id, answer := range answers {
+		responses[id] = answer
+	}
+
+	closeExamAttempt(context, exam, examAttempt, responses, models.AttemptSubmitted, now)
+}
 
-	scoreOutOf10 := (totalScore / totalQuestions) * 10.0
-	scoreOutOf10Rounded := math.Round(scoreOutOf10*10) / 10
-	percentageScore := (totalScore / totalQuestions) * 100.0
-	passed := percentageScore >= 70.0
+// Grades the attempt, closes it with the status and writes the response.
+// Attempts that ran out of time do not complete the activity.
+func closeExamAttempt(context *gin.Context, exam *models.Exam, examAttempt *models.ExamAttempt, responses map[string]internal.Answer, status string, now time.Time) {
+	grades, err := Generator.GradeExam(generationContext(context, examAttempt.UserId, exam.Language), exam.Questions, responses)
+	if err != nil {
+		context.JSON(generationErrorStatus(err), gin.H{
+			"message": "Failed to grade answers: " + err.Error(),
+		})
+		return
+	}
 
-	examAttempt.Answers = answers
-	examAttempt.Time = userResponse.Time
-	examAttempt.Score = scoreOutOf10Rounded
-	examAttempt.Passed = passed
+	examAttempt.Grade(exam.Questions, responses, grades)
 
-	err = examAttempt.Update()
+	closed, err := examAttempt.Close(status, now)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
 			"message": "Failed to update exam attempt: " + err.Error(),
@@ -175,8 +402,129 @@ func SubmitExamAttempt(context *gin.Context) {
 		return
 	}
 
+	if !closed {
+		context.JSON(http.StatusConflict, gin.H{
+			"message": "Exam attempt was already submitted",
+		})
+		return
+	}
+
+	err = models.UpdateExamScores(exam)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to update exam scores: " + err.Error(),
+		})
+		return
+	}
+
+	if status == models.AttemptExpired {
+		context.JSON(http.StatusConflict, gin.H{
+			"message": "Time is up, the exam was closed with the answers saved before the deadline",
+			"data":    examAttempt,
+			"scores":  exam.Scores,
+		})
+		return
+	}
+
+	err = completeActivity(exam.ActividyId, examAttempt.UserId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to update activity progress: " + err.Error(),
+		})
+		return
+	}
+
 	context.JSON(http.StatusOK, gin.H{
 		"message": "Exam submitted successfully",
 		"data":    examAttempt,
+		"scores":  exam.Scores,
+	})
+}
+
+// Answers for another revision of the exam would be graded against questions
+// the user never saw. Attempts started before questions had ids give the
+// exam its ids here. Writes the error response itself and returns false
+// when the exam changed since the attempt started.
+func checkExamRevision(context *gin.Context, exam *models.Exam, examAttempt *models.ExamAttempt) bool {
+	err := exam.AssignQuestionIds()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to assign question ids: " + err.Error(),
+		})
+		return false
+	}
+
+	if examAttempt.Matches(exam) {
+		return true
+	}
+
+	context.JSON(http.StatusConflict, gin.H{
+		"message": "The exam changed since this attempt started, start a new attempt",
 	})
+	return false
+}
+
+// Checks the answers are keyed by questions of the exam and have the form
+// their type asks for. Writes the error response itself and returns false
+// when they are not.
+func checkExamAnswers(context *gin.Context, exam *models.Exam, answers map[string]internal.Answer) bool {
+	questions := make(map[string]internal.ExamQuestion, len(exam.Questions))
+	for _, question := range exam.Questions {
+		questions[question.Id] = question
+	}
+
+	for id, answer := range answers {
+		question, ok := questions[id]
+		if !ok || id == "" {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"message": "Unknown question " + id,
+			})
+			return false
+		}
+		if !question.Accepts(answer) {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid answer for question " + id,
+			})
+			return false
+		}
+	}
+
+	return true
+}
+
+// Loads the attempt named in the route, or the latest attempt of the exam
+// when there is none. Writes the error response itself and returns false
+// when something is wrong.
+func findExamAttempt(context *gin.Context, examId bson.ObjectID, userId bson.ObjectID) (*models.ExamAttempt, bool) {
+	var examAttempt *models.ExamAttempt
+	var err error
+
+	if context.Param("attemptId") == "" {
+		examAttempt, err = models.GetAttemptByExamId(examId)
+	} else {
+		var attemptId bson.ObjectID
+		attemptId, err = bson.ObjectIDFromHex(context.Param("attemptId"))
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Invalid exam attempt ID format",
+			})
+			return nil, false
+		}
+		examAttempt, err = models.GetExamAttemptById(attemptId)
+	}
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not fetch exam attempt",
+		})
+		return nil, false
+	}
+
+	if examAttempt.UserId != userId || examAttempt.ExamId != examId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Exam attempt does not belong to you",
+		})
+		return nil, false
+	}
+
+	return examAttempt, true
 }
@@ -2,10 +2,13 @@ package controllers
 
 import (
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"prepai.app/internal"
+	"prepai.app/jobs"
 	"prepai.app/models"
 )
 
@@ -82,30 +85,99 @@ func CreateExam(context *gin.Context) {
 		return
 	}
 
-	result, err := internal.GenerateExam(exam.Subject, exam.Difficulty, exam.Type)
+	if !slices.Contains(internal.ExamTypes, exam.Type) {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Exam type must be one of " + strings.Join(internal.ExamTypes, ", "),
+		})
+		return
+	}
+
+	language, ok := requestLanguage(context, userId, exam.Language)
+	if !ok {
+		return
+	}
+
+	enqueueJob(context, models.JobCreateExam, userId, jobs.ExamPayload{
+		Subject:    exam.Subject,
+		Difficulty: exam.Difficulty,
+		Type:       exam.Type,
+		Language:   language,
+		TimeLimit:  exam.TimeLimit,
+	}, "Exam generation started")
+}
+
+// Generates the exam in the request instead of a background job, pushing
+// each question as a server-sent event as soon as it is written.
+func StreamExam(context *gin.Context) {
+	userId, err := GetUserId(context)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"message": err.Error(),
 		})
 		return
 	}
 
+	var exam models.Exam
+	err = context.ShouldBindJSON(&exam)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not parse request",
+		})
+		return
+	}
+
+	if exam.Subject == "" || exam.Difficulty == "" || exam.Type == "" {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Missing either subject, difficulty or exam type",
+		})
+		return
+	}
+
+	if !slices.Contains(internal.ExamTypes, exam.Type) {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Exam type must be one of " + strings.Join(internal.ExamTypes, ", "),
+		})
+		return
+	}
+
+	language, ok := requestLanguage(context, userId, exam.Language)
+	if !ok {
+		return
+	}
+
+	if !checkQuota(context, userId) {
+		return
+	}
+
+	emit := startStream(context)
+
+	result, err := Generator.StreamExam(generationContext(context, userId, language), exam.Subject, exam.Difficulty, exam.Type, emit)
+	if err != nil {
+		streamError(context, generationErrorStatus(err), err.Error())
+		return
+	}
+
 	exam.Title = result.Title
+	exam.Language = language
 	exam.Questions = result.Questions
+	exam.Prompt = &result.Prompt
+	exam.ShuffleSeed = result.Seed
+	exam.CorrectPositions = result.CorrectPositions()
 	exam.UserId = userId
 
 	err = exam.Save()
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		streamError(context, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	context.JSON(http.StatusCreated, gin.H{
-		"message": "Exam created successfully",
-		"data":    exam,
-	})
+	saved, err := models.GetExamById(exam.Id, false)
+	if err != nil {
+		streamError(context, http.StatusInternalServerError, "Could not fetch exam.")
+		return
+	}
+
+	streamDone(context, "Exam created successfully", saved)
 }
 
 func UpdateExam(context *gin.Context) {
@@ -139,7 +211,11 @@ func UpdateExam(context *gin.Context) {
 		return
 	}
 
-	err = context.ShouldBindJSON(&exam)
+	var updatedExam struct {
+		Title  *string `json:"title"`
+		Pinned *bool   `json:"pinned"`
+	}
+	err = context.ShouldBindJSON(&updatedExam)
 	if err != nil {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "Could not parse request",
@@ -147,6 +223,13 @@ func UpdateExam(context *gin.Context) {
 		return
 	}
 
+	if updatedExam.Title != nil {
+		exam.Title = *updatedExam.Title
+	}
+	if updatedExam.Pinned != nil {
+		exam.Pinned = *updatedExam.Pinned
+	}
+
 	err = exam.Update()
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
@@ -234,26 +317,63 @@ func RegenerateExam(context *gin.Context) {
 		return
 	}
 
-	results, err := internal.GenerateExam(exam.Subject, exam.Difficulty, exam.Type)
+	enqueueJob(context, models.JobRegenerateExam, userId, jobs.RegeneratePayload{
+		Id: exam.Id,
+	}, "Exam regeneration started")
+}
+
+// Translates the exam into another language as a new exam, the original is
+// kept as it is.
+func TranslateExam(context *gin.Context) {
+	userId, err := GetUserId(context)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"message": err.Error(),
 		})
+		return
+	}
+
+	examId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid exam ID format",
+		})
+		return
 	}
 
-	exam.Title = results.Title
-	exam.Questions = results.Questions
+	exam, err := models.GetExamById(examId, false)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch exam"})
+		return
+	}
 
-	err = exam.Update()
+	if exam.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Exam does not belong to you",
+		})
+		return
+	}
+
+	language, ok := bindTranslateLanguage(context, exam.Language)
+	if !ok {
+		return
+	}
+
+	enqueueJob(context, models.JobTranslateExam, userId, jobs.TranslatePayload{
+		Id:       exam.Id,
+		Language: language,
+	}, "Exam translation started")
+}
+
+// Reports where the correct answers of the generated exams ended up.
+func GetCorrectPositionStats(context *gin.Context) {
+	stats, err := models.GetCorrectPositionStats()
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
+			"message": "Could not fetch correct position statistics",
 		})
 		return
 	}
 
-	context.JSON(http.StatusCreated, gin.H{
-		"message": "Exam questions regenerated successfully",
-		"data":    exam,
-	})
+	context.JSON(http.StatusOK, stats)
 }
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"prepai.app/models"
+)
+
+// Reports the outcome of every variant of the running prompt experiments.
+func GetExperiments(context *gin.Context) {
+	experiments := Generator.Prompts.Experiments()
+	results := make([]models.ExperimentResult, 0, len(experiments))
+
+	for _, experiment := range experiments {
+		result, err := models.GetExperimentResult(experiment)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Could not fetch results of experiment " + experiment.Name,
+			})
+			return
+		}
+		results = append(results, *result)
+	}
+
+	context.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/models"
+	"prepai.app/prompts"
+)
+
+type flagRequest struct {
+	Reason string `json:"reason"`
+}
+
+func FlagExam(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	examId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid exam ID format",
+		})
+		return
+	}
+
+	exam, err := models.GetExamById(examId, false)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not fetch exam."})
+		return
+	}
+
+	if exam.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Exam does not belong to you",
+		})
+		return
+	}
+
+	saveFlag(context, userId, models.ContentExam, exam.Id, exam.Prompt)
+}
+
+func FlagInterview(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	interviewId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid interview ID format",
+		})
+		return
+	}
+
+	interview, err := models.GetInterviewById(interviewId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not fetch interview."})
+		return
+	}
+
+	if interview.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Interview does not belong to you",
+		})
+		return
+	}
+
+	saveFlag(context, userId, models.ContentInterview, interview.Id, interview.Prompt)
+}
+
+func FlagInterviewFeedback(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	interviewId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid interview ID format",
+		})
+		return
+	}
+
+	interviewAttempt, err := models.GetAttemptByInterviewId(interviewId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not fetch interview attempt",
+		})
+		return
+	}
+
+	if interviewAttempt.UserId != userId {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "This interview attempt does not belong to you",
+		})
+		return
+	}
+
+	if len(interviewAttempt.Answers) == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "This interview attempt has no feedback yet",
+		})
+		return
+	}
+
+	saveFlag(context, userId, models.ContentFeedback, interviewAttempt.Id, interviewAttempt.Prompt)
+}
+
+func saveFlag(context *gin.Context, userId bson.ObjectID, contentType string, contentId bson.ObjectID, prompt *prompts.Ref) {
+	var request flagRequest
+	// The reason is optional, an empty body is fine
+	_ = context.ShouldBindJSON(&request)
+
+	flag := models.Flag{
+		ContentType: contentType,
+		ContentId:   contentId,
+		Reason:      request.Reason,
+		Prompt:      prompt,
+		UserId:      userId,
+		CreatedAt:   time.Now(),
+	}
+
+	err := flag.Save()
+	if err != nil {
+		if err == models.ErrAlreadyFlagged {
+			context.JSON(http.StatusConflict, gin.H{
+				"message": "You already flagged this content",
+			})
+			return
+		}
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "Content flagged successfully",
+		"data":    flag,
+	})
+}
@@ -1,12 +1,22 @@
 package controllers
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/internal"
+	"prepai.app/models"
+	"prepai.app/providers"
 )
 
+// Generator is shared by every controller that creates AI content, it is set
+// up in main with the configured model provider.
+var Generator *internal.Generator
+
 func GetUserId(context *gin.Context) (bson.ObjectID, error) {
 	userIdInterface, exists := context.Get("userId")
 	if !exists {
@@ -20,3 +30,117 @@ func GetUserId(context *gin.Context) (bson.ObjectID, error) {
 
 	return userId, nil
 }
+
+// Invalid model output is the provider's fault, not ours. A model that is
+// down or too slow is reported as such so clients know to try again.
+func generationErrorStatus(err error) int {
+	var providerErr *providers.Error
+	if errors.As(err, &providerErr) {
+		switch providerErr.Code {
+		case providers.CodeUnavailable, providers.CodeBusy:
+			return http.StatusServiceUnavailable
+		case providers.CodeTimeout:
+			return http.StatusGatewayTimeout
+		default:
+			return http.StatusBadGateway
+		}
+	}
+
+	var validationErr *internal.ValidationError
+	if errors.As(err, &validationErr) {
+		return http.StatusBadGateway
+	}
+
+	var quotaErr *models.QuotaError
+	if errors.As(err, &quotaErr) {
+		return http.StatusTooManyRequests
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Refuses requests that would start a generation once the user ran out of
+// tokens. Writes the error response itself and returns false in that case.
+func checkQuota(context *gin.Context, userId bson.ObjectID) bool {
+	err := models.CheckQuota(userId)
+	if err != nil {
+		context.JSON(generationErrorStatus(err), gin.H{
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+// The language to generate content in: the one the request asks for, the
+// language query parameter or the user's preference, in that order. Writes
+// the error response itself and returns false when it is not supported.
+func requestLanguage(context *gin.Context, userId bson.ObjectID, requested string) (string, bool) {
+	if requested == "" {
+		requested = context.Query("language")
+	}
+	if requested == "" {
+		user, err := models.GetUser(userId)
+		if err != nil || user.Language == "" {
+			return internal.DefaultLanguage, true
+		}
+		requested = user.Language
+	}
+
+	language, ok := internal.ParseLanguage(requested)
+	if !ok {
+		unsupportedLanguage(context)
+		return "", false
+	}
+
+	return language, true
+}
+
+// Context for generations made on behalf of the user in the language.
+func generationContext(context *gin.Context, userId bson.ObjectID, language string) context.Context {
+	return internal.WithLanguage(internal.WithUserId(context.Request.Context(), userId), language)
+}
+
+type translateRequest struct {
+	Language string `json:"language"`
+}
+
+// Reads the language to translate content into. Writes the error response
+// itself and returns false when it is missing, not supported or the one the
+// content is already in.
+func bindTranslateLanguage(context *gin.Context, current string) (string, bool) {
+	var request translateRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil || request.Language == "" {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Missing language to translate into",
+		})
+		return "", false
+	}
+
+	language, ok := internal.ParseLanguage(request.Language)
+	if !ok {
+		unsupportedLanguage(context)
+		return "", false
+	}
+
+	// Content from before languages were stored was generated in the default one
+	if current == "" {
+		current = internal.DefaultLanguage
+	}
+	if language == current {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Content is already in that language",
+		})
+		return "", false
+	}
+
+	return language, true
+}
+
+func unsupportedLanguage(context *gin.Context) {
+	context.JSON(http.StatusBadRequest, gin.H{
+		"message": "Unsupported language, use one of: " + strings.Join(internal.LanguageCodes(), ", "),
+	})
+}
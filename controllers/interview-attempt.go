@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -61,9 +64,39 @@ func CreateInterviewAttempt(context *gin.Context) {
 		return
 	}
 
+	interview, err := models.GetInterviewById(interviewId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not fetch interview",
+		})
+		return
+	}
+
+	if interview.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Interview does not belong to you",
+		})
+		return
+	}
+
+	err = startActivity(interview.ActividyId, userId)
+	if err != nil {
+		if err == errActivityLocked {
+			context.JSON(http.StatusForbidden, gin.H{
+				"message": "This interview belongs to a locked step",
+			})
+			return
+		}
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to start activity: " + err.Error(),
+		})
+		return
+	}
+
 	var interviewAttempt models.InterviewAttempt
 	interviewAttempt.UserId = userId
 	interviewAttempt.InterviewId = interviewId
+	interviewAttempt.Language = interview.Language
 
 	err = interviewAttempt.Save()
 	if err != nil {
@@ -86,12 +119,91 @@ func CreateInterviewAttemptFeedback(context *gin.Context) {
 		})
 	}
 
+	interviewAttempt, userResponses, ok := bindInterviewFeedback(context, userId)
+	if !ok {
+		return
+	}
+
+	// Feedback is written in the language of the interview
+	language, ok := requestLanguage(context, userId, interviewAttempt.Language)
+	if !ok {
+		return
+	}
+
+	results, err := Generator.GenerateInterviewFeedback(generationContext(context, userId, language), userResponses)
+	if err != nil {
+		context.JSON(generationErrorStatus(err), gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	err = saveInterviewFeedback(interviewAttempt, userId, userResponses, results)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Interview feedback generated successfully",
+		"data":    interviewAttempt,
+	})
+}
+
+// Same as CreateInterviewAttemptFeedback but each answer's feedback is sent
+// as a server-sent event as soon as it is generated.
+func StreamInterviewAttemptFeedback(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	interviewAttempt, userResponses, ok := bindInterviewFeedback(context, userId)
+	if !ok {
+		return
+	}
+
+	language, ok := requestLanguage(context, userId, interviewAttempt.Language)
+	if !ok {
+		return
+	}
+
+	if !checkQuota(context, userId) {
+		return
+	}
+
+	emit := startStream(context)
+
+	results, err := Generator.StreamInterviewFeedback(generationContext(context, userId, language), userResponses, emit)
+	if err != nil {
+		streamError(context, generationErrorStatus(err), err.Error())
+		return
+	}
+
+	err = saveInterviewFeedback(interviewAttempt, userId, userResponses, results)
+	if err != nil {
+		streamError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	streamDone(context, "Interview feedback generated successfully", interviewAttempt)
+}
+
+// Reads the answers and loads the attempt they belong to. Answers with a
+// question id get the question text from the interview. Writes the error
+// response itself and returns false when something is wrong.
+func bindInterviewFeedback(context *gin.Context, userId bson.ObjectID) (*models.InterviewAttempt, []internal.UserInterviewResponse, bool) {
 	interviewId, err := bson.ObjectIDFromHex(context.Param("id"))
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"message": "Invalid interview ID format",
 		})
-		return
+		return nil, nil, false
 	}
 
 	var userResponses []internal.UserInterviewResponse
@@ -100,7 +212,14 @@ func CreateInterviewAttemptFeedback(context *gin.Context) {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "Could not parse request data.",
 		})
-		return
+		return nil, nil, false
+	}
+
+	if len(userResponses) == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Responses array cannot be empty",
+		})
+		return nil, nil, false
 	}
 
 	interviewAttempt, err := models.GetAttemptByInterviewId(interviewId)
@@ -108,31 +227,71 @@ func CreateInterviewAttemptFeedback(context *gin.Context) {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "Could not fetch interview attempt",
 		})
-		return
+		return nil, nil, false
 	}
 
 	if interviewAttempt.UserId != userId {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "This interview attempt does not belong to you",
 		})
-		return
+		return nil, nil, false
+	}
+
+	if !resolveInterviewQuestions(context, interviewId, userResponses) {
+		return nil, nil, false
+	}
 
+	return interviewAttempt, userResponses, true
+}
+
+// Older clients send the question text alone, it is taken as it is.
+func resolveInterviewQuestions(context *gin.Context, interviewId bson.ObjectID, userResponses []internal.UserInterviewResponse) bool {
+	byId := slices.ContainsFunc(userResponses, func(userResponse internal.UserInterviewResponse) bool {
+		return userResponse.QuestionId != ""
+	})
+	if !byId {
+		return true
 	}
 
-	results, err := internal.GenerateInterviewFeedback(userResponses)
+	interview, err := models.GetInterviewById(interviewId)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not fetch interview",
 		})
-		return
+		return false
+	}
+
+	questions := make(map[string]string, len(interview.Questions))
+	for _, question := range interview.Questions {
+		questions[question.Id] = question.Question
+	}
+
+	for i, userResponse := range userResponses {
+		if userResponse.QuestionId == "" {
+			continue
+		}
+
+		question, ok := questions[userResponse.QuestionId]
+		if !ok {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"message": "Unknown question " + userResponse.QuestionId,
+			})
+			return false
+		}
+		userResponses[i].Question = question
 	}
 
+	return true
+}
+
+func saveInterviewFeedback(interviewAttempt *models.InterviewAttempt, userId bson.ObjectID, userResponses []internal.UserInterviewResponse, results internal.InterviewFeedbackResponse) error {
 	answers := make([]models.InterviewAnswer, len(userResponses))
 	totalScore := 0.0
 
 	for i, userResponse := range userResponses {
 		feedback := results.Feedbacks[i]
 		answers[i] = models.InterviewAnswer{
+			QuestionId:   userResponse.QuestionId,
 			Question:     userResponse.Question,
 			UserResponse: userResponse.Answer,
 			Feedback:     feedback.Feedback,
@@ -151,17 +310,22 @@ func CreateInterviewAttemptFeedback(context *gin.Context) {
 	interviewAttempt.Analysis = results.Analysis
 	interviewAttempt.AreasToImprove = results.AreasToImprove
 	interviewAttempt.Strengths = results.Strengths
+	interviewAttempt.Prompt = &results.Prompt
 
-	err = interviewAttempt.Update()
+	err := interviewAttempt.Update()
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Failed to update interview attempt: " + err.Error(),
-		})
-		return
+		return fmt.Errorf("Failed to update interview attempt: %v", err)
 	}
 
-	context.JSON(http.StatusOK, gin.H{
-		"message": "Interview feedback generated successfully",
-		"data":    interviewAttempt,
-	})
+	interview, err := models.GetInterviewById(interviewAttempt.InterviewId)
+	if err != nil {
+		return errors.New("Could not fetch interview")
+	}
+
+	err = completeActivity(interview.ActividyId, userId)
+	if err != nil {
+		return fmt.Errorf("Failed to update activity progress: %v", err)
+	}
+
+	return nil
 }
@@ -5,7 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"prepai.app/internal"
+	"prepai.app/jobs"
 	"prepai.app/models"
 )
 
@@ -83,31 +83,54 @@ func CreateInterview(context *gin.Context) {
 		return
 	}
 
-	results, err := internal.GenerateInterview(interview.JobRole, interview.JobLevel, interview.Topics)
+	language, ok := requestLanguage(context, userId, interview.Language)
+	if !ok {
+		return
+	}
+
+	enqueueJob(context, models.JobCreateInterview, userId, jobs.InterviewPayload{
+		JobRole:  interview.JobRole,
+		JobLevel: interview.JobLevel,
+		Topics:   interview.Topics,
+		Language: language,
+	}, "Interview generation started")
+}
+
+func RegenerateInterview(context *gin.Context) {
+	userId, err := GetUserId(context)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"message": err.Error(),
 		})
 	}
 
-	interview.Title = results.Title
-	interview.Questions = results.Questions
+	interviewId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid interview ID format",
+		})
+		return
+	}
 
-	err = interview.Save()
+	interview, err := models.GetInterviewById(interviewId)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch interview. Try again later."})
+		return
+	}
+
+	if interview.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Interview does not belong to you",
 		})
 		return
 	}
 
-	context.JSON(http.StatusCreated, gin.H{
-		"message": "Interview created successfully",
-		"data":    interview,
-	})
+	enqueueJob(context, models.JobRegenerateInterview, userId, jobs.RegeneratePayload{
+		Id: interview.Id,
+	}, "Interview regeneration started")
 }
 
-func RegenerateInterview(context *gin.Context) {
+func UpdateInterview(context *gin.Context) {
 	userId, err := GetUserId(context)
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -136,16 +159,13 @@ func RegenerateInterview(context *gin.Context) {
 		return
 	}
 
-	results, err := internal.GenerateInterview(interview.JobRole, interview.JobLevel, interview.Topics)
+	err = context.ShouldBindJSON(&interview)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not parse request data.",
 		})
 	}
 
-	interview.Title = results.Title
-	interview.Questions = results.Questions
-
 	err = interview.Update()
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
@@ -155,12 +175,12 @@ func RegenerateInterview(context *gin.Context) {
 	}
 
 	context.JSON(http.StatusCreated, gin.H{
-		"message": "Interview questions regenerated successfully",
+		"message": "Interview updated successfully",
 		"data":    interview,
 	})
 }
 
-func UpdateInterview(context *gin.Context) {
+func DeleteInterview(context *gin.Context) {
 	userId, err := GetUserId(context)
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -189,14 +209,7 @@ func UpdateInterview(context *gin.Context) {
 		return
 	}
 
-	err = context.ShouldBindJSON(&interview)
-	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "Could not parse request data.",
-		})
-	}
-
-	err = interview.Update()
+	err = interview.Delete()
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
@@ -205,17 +218,19 @@ func UpdateInterview(context *gin.Context) {
 	}
 
 	context.JSON(http.StatusCreated, gin.H{
-		"message": "Interview updated successfully",
-		"data":    interview,
+		"message": "Interview deleted successfully",
 	})
 }
 
-func DeleteInterview(context *gin.Context) {
+// Translates the interview into another language as a new interview, the
+// original is kept as it is.
+func TranslateInterview(context *gin.Context) {
 	userId, err := GetUserId(context)
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"message": err.Error(),
 		})
+		return
 	}
 
 	interviewId, err := bson.ObjectIDFromHex(context.Param("id"))
@@ -239,15 +254,13 @@ func DeleteInterview(context *gin.Context) {
 		return
 	}
 
-	err = interview.Delete()
-	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+	language, ok := bindTranslateLanguage(context, interview.Language)
+	if !ok {
 		return
 	}
 
-	context.JSON(http.StatusCreated, gin.H{
-		"message": "Interview deleted successfully",
-	})
+	enqueueJob(context, models.JobTranslateInterview, userId, jobs.TranslatePayload{
+		Id:       interview.Id,
+		Language: language,
+	}, "Interview translation started")
 }
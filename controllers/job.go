@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/jobs"
+	"prepai.app/models"
+)
+
+func GetJob(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	jobId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid job ID format",
+		})
+		return
+	}
+
+	job, err := models.GetJobById(jobId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch job. Try again later."})
+		return
+	}
+
+	if job.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Job does not belong to you",
+		})
+		return
+	}
+
+	if job.Status != models.JobCompleted {
+		context.JSON(http.StatusOK, gin.H{
+			"job": job,
+		})
+		return
+	}
+
+	result, err := getJobResult(job.ResultType, job.ResultId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Could not fetch job result",
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"job":  job,
+		"data": result,
+	})
+}
+
+// Stores a generation job and answers 202 so the client can poll /jobs/:id.
+func enqueueJob(context *gin.Context, jobType string, userId bson.ObjectID, payload any, message string) {
+	if !checkQuota(context, userId) {
+		return
+	}
+
+	job, err := jobs.Enqueue(jobType, userId, payload)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	context.JSON(http.StatusAccepted, gin.H{
+		"message": message,
+		"job_id":  job.Id,
+		"data":    job,
+	})
+}
+
+func getJobResult(resultType string, resultId bson.ObjectID) (any, error) {
+	if resultType == models.ContentResume {
+		return models.GetResumeById(resultId)
+	}
+
+	result, _, err := getActivityContent(resultType, resultId)
+	return result, err
+}
@@ -0,0 +1,442 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/models"
+)
+
+func GetLearningPaths(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	paths, err := models.GetAllUserLearningPaths(userId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch learning paths. Try again later."})
+		return
+	}
+
+	context.JSON(http.StatusOK, paths)
+}
+
+func GetLearningPath(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	pathId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid learning path ID format",
+		})
+		return
+	}
+
+	path, err := models.GetLearningPathById(pathId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch learning path. Try again later."})
+		return
+	}
+
+	if path.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Learning path does not belong to you",
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, path)
+}
+
+func CreateLearningPath(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var path models.LearningPath
+	err = context.ShouldBindJSON(&path)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not parse request data.",
+		})
+		return
+	}
+
+	if path.JobRole == "" || path.JobLevel == "" || len(path.Topics) == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Missing either job role, level or topics",
+		})
+		return
+	}
+
+	language, ok := requestLanguage(context, userId, path.Language)
+	if !ok {
+		return
+	}
+
+	results, err := Generator.GenerateModules(generationContext(context, userId, language), path.JobRole, path.JobLevel, path.JobDescription, path.Topics)
+	if err != nil {
+		context.JSON(generationErrorStatus(err), gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	modules := make([]models.PathModule, len(results.Modules))
+	for i, module := range results.Modules {
+		modules[i] = models.PathModule{
+			Title:     module.Title,
+			Objective: module.Objective,
+			Order:     module.Order,
+			Topic:     module.Topic,
+		}
+	}
+
+	path.Title = path.JobRole + " (" + path.JobLevel + ")"
+	path.Modules = modules
+	path.Language = language
+	path.UserId = userId
+
+	err = path.Save()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "Learning path created successfully",
+		"data":    path,
+	})
+}
+
+func GenerateModuleSteps(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	pathId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid learning path ID format",
+		})
+		return
+	}
+
+	order, err := strconv.ParseInt(context.Param("order"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid module order",
+		})
+		return
+	}
+
+	path, err := models.GetLearningPathById(pathId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch learning path. Try again later."})
+		return
+	}
+
+	if path.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Learning path does not belong to you",
+		})
+		return
+	}
+
+	module := path.GetModule(order)
+	if module == nil {
+		context.JSON(http.StatusNotFound, gin.H{
+			"message": "Module not found",
+		})
+		return
+	}
+
+	// Steps are only generated once, the client gets the stored ones afterwards
+	if len(module.Steps) > 0 {
+		context.JSON(http.StatusOK, gin.H{
+			"message": "Module steps already generated",
+			"data":    module,
+		})
+		return
+	}
+
+	// Steps are written in the language the path was created in
+	language, ok := requestLanguage(context, userId, path.Language)
+	if !ok {
+		return
+	}
+
+	results, err := Generator.GenerateSteps(generationContext(context, userId, language), module.Title, module.Objective, splitTopics(module.Topic))
+	if err != nil {
+		context.JSON(generationErrorStatus(err), gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if len(results.Steps) == 0 {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "No steps were generated for this module",
+		})
+		return
+	}
+
+	activities := make([]models.Activity, len(results.Steps))
+	for i, step := range results.Steps {
+		activities[i] = models.Activity{
+			Title:       step.Title,
+			ContentType: models.ContentTypeFromStep(step.Type),
+			Status:      models.ActivityLocked,
+			Difficulty:  step.Difficulty,
+			PathId:      path.Id,
+			ModuleOrder: module.Order,
+			StepOrder:   step.Order,
+			UserId:      userId,
+		}
+	}
+
+	pathActivities, err := models.GetActivitiesByPathId(path.Id)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Only the first step of an unlocked module is playable right away
+	progress := path.Progress(append(pathActivities, activities...))
+	if progress.GetModule(module.Order).Unlocked {
+		first := 0
+		for i := range activities {
+			if activities[i].StepOrder < activities[first].StepOrder {
+				first = i
+			}
+		}
+		activities[first].Status = models.ActivityAvailable
+	}
+
+	err = models.SaveActivities(activities)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	steps := make([]models.PathStep, len(results.Steps))
+	for i, step := range results.Steps {
+		steps[i] = models.PathStep{
+			Title:      step.Title,
+			Type:       step.Type,
+			Order:      step.Order,
+			Difficulty: step.Difficulty,
+			ActivityId: activities[i].Id,
+		}
+	}
+
+	claimed, err := path.SetModuleSteps(module.Order, steps)
+	if err != nil {
+		models.DeleteActivities(activities)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Another request generated the steps first, keep those
+	if !claimed {
+		models.DeleteActivities(activities)
+
+		path, err = models.GetLearningPathById(pathId)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch learning path. Try again later."})
+			return
+		}
+
+		context.JSON(http.StatusOK, gin.H{
+			"message": "Module steps already generated",
+			"data":    path.GetModule(order),
+		})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "Module steps generated successfully",
+		"data":    module,
+	})
+}
+
+func GetLearningPathProgress(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	pathId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid learning path ID format",
+		})
+		return
+	}
+
+	path, err := models.GetLearningPathById(pathId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch learning path. Try again later."})
+		return
+	}
+
+	if path.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Learning path does not belong to you",
+		})
+		return
+	}
+
+	activities, err := models.GetActivitiesByPathId(path.Id)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch learning path activities."})
+		return
+	}
+
+	context.JSON(http.StatusOK, path.Progress(activities))
+}
+
+func UpdateLearningPath(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	pathId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid learning path ID format",
+		})
+		return
+	}
+
+	path, err := models.GetLearningPathById(pathId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch learning path. Try again later."})
+		return
+	}
+
+	if path.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Learning path does not belong to you",
+		})
+		return
+	}
+
+	var updatedPath struct {
+		Title  *string `json:"title"`
+		Pinned *bool   `json:"pinned"`
+	}
+	err = context.ShouldBindJSON(&updatedPath)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"message": "Could not parse request data.",
+		})
+		return
+	}
+
+	if updatedPath.Title != nil {
+		path.Title = *updatedPath.Title
+	}
+	if updatedPath.Pinned != nil {
+		path.Pinned = *updatedPath.Pinned
+	}
+
+	err = path.Update()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Learning path updated successfully",
+		"data":    path,
+	})
+}
+
+func DeleteLearningPath(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	pathId, err := bson.ObjectIDFromHex(context.Param("id"))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Invalid learning path ID format",
+		})
+		return
+	}
+
+	path, err := models.GetLearningPathById(pathId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch learning path. Try again later."})
+		return
+	}
+
+	if path.UserId != userId {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Learning path does not belong to you",
+		})
+		return
+	}
+
+	err = models.DeleteActivitiesByPathId(path.Id)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	err = path.Delete()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Learning path deleted successfully",
+	})
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"prepai.app/providers"
+)
+
+// Limiter queues the calls to every model, it is set up in main with the
+// provider.
+var Limiter *providers.Limiter
+
+// Reports the queue depth and wait times of every model called so far.
+func GetModelStats(context *gin.Context) {
+	if Limiter == nil {
+		context.JSON(http.StatusOK, []providers.LimiterStats{})
+		return
+	}
+
+	context.JSON(http.StatusOK, Limiter.Stats())
+}
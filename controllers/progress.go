@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/models"
+)
+
+var errActivityLocked = errors.New("activity is locked")
+
+// Moves the activity backing an exam or interview to in progress. Content
+// that is not part of any activity is ignored.
+func startActivity(activityId bson.ObjectID, userId bson.ObjectID) error {
+	if activityId.IsZero() {
+		return nil
+	}
+
+	activity, err := models.GetActivityById(activityId)
+	if err != nil {
+		return err
+	}
+
+	if activity.UserId != userId {
+		return errors.New("activity does not belong to you")
+	}
+
+	switch activity.Status {
+	case models.ActivityLocked:
+		return errActivityLocked
+	case models.ActivityAvailable:
+		activity.Status = models.ActivityInProgress
+		return activity.Update()
+	default:
+		return nil
+	}
+}
+
+// Completes the activity backing an exam or interview and unlocks the next
+// steps of its learning path.
+func completeActivity(activityId bson.ObjectID, userId bson.ObjectID) error {
+	if activityId.IsZero() {
+		return nil
+	}
+
+	activity, err := models.GetActivityById(activityId)
+	if err != nil {
+		return err
+	}
+
+	if activity.UserId != userId {
+		return errors.New("activity does not belong to you")
+	}
+
+	if activity.Status == models.ActivityLocked {
+		return errActivityLocked
+	}
+
+	return activity.Complete()
+}
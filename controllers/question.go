@@ -5,7 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"prepai.app/internal"
+	"prepai.app/jobs"
 	"prepai.app/models"
 )
 
@@ -81,33 +81,15 @@ func CreateQuestion(context *gin.Context) {
 		return
 	}
 
-	result, err := internal.GenerateQuestionAnalysis(question.Question)
-	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
-		return
-	}
-
-	question.UserId = userId
-	question.Type = result.Type
-	question.Difficulty = result.Difficulty
-	question.Explanation = result.Explanation
-	question.ExpectedLength = result.ExpectedLength
-	question.IdealAnswer = result.IdealAnswer
-
-	err = question.Save()
-	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+	language, ok := requestLanguage(context, userId, question.Language)
+	if !ok {
 		return
 	}
 
-	context.JSON(http.StatusCreated, gin.H{
-		"message": "Question created successfully",
-		"data":    question,
-	})
+	enqueueJob(context, models.JobCreateQuestion, userId, jobs.QuestionPayload{
+		Question: question.Question,
+		Language: language,
+	}, "Question analysis started")
 }
 
 func DeleteQuestion(context *gin.Context) {
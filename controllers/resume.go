@@ -1,12 +1,13 @@
 package controllers
 
 import (
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"prepai.app/internal"
+	"prepai.app/jobs"
 	"prepai.app/models"
 )
 
@@ -68,12 +69,85 @@ func CreateResume(context *gin.Context) {
 		return
 	}
 
+	resume, jobDescription, ok := bindResumeForm(context)
+	if !ok {
+		return
+	}
+
+	language, ok := requestLanguage(context, userId, context.PostForm("language"))
+	if !ok {
+		return
+	}
+
+	enqueueJob(context, models.JobCreateResume, userId, jobs.ResumePayload{
+		Resume:         resume,
+		JobDescription: jobDescription,
+		Language:       language,
+	}, "Resume analysis started")
+}
+
+// Analyzes the resume in the request instead of a background job, pushing
+// each field of the analysis as a server-sent event.
+func StreamResume(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	file, jobDescription, ok := bindResumeForm(context)
+	if !ok {
+		return
+	}
+
+	language, ok := requestLanguage(context, userId, context.PostForm("language"))
+	if !ok {
+		return
+	}
+
+	if !checkQuota(context, userId) {
+		return
+	}
+
+	emit := startStream(context)
+
+	result, err := Generator.StreamResumeAnalysis(generationContext(context, userId, language), file, jobDescription, emit)
+	if err != nil {
+		streamError(context, generationErrorStatus(err), err.Error())
+		return
+	}
+
+	resume := models.Resume{
+		Title:                  result.Title,
+		OverallScore:           result.OverallScore,
+		AnalysisSummary:        result.AnalysisSummary,
+		ImprovementSuggestions: result.ImprovementSuggestions,
+		Metrics:                result.Metrics,
+		Language:               language,
+		Prompt:                 &result.Prompt,
+		UserId:                 userId,
+	}
+
+	err = resume.Save()
+	if err != nil {
+		streamError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	streamDone(context, "Resume analyzed successfully", resume)
+}
+
+// Reads the uploaded PDF and the job description. Writes the error response
+// itself and returns false when something is wrong.
+func bindResumeForm(context *gin.Context) ([]byte, string, bool) {
 	header, err := context.FormFile("resume")
 	if err != nil {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "Missing resume file or error uploading",
 		})
-		return
+		return nil, "", false
 	}
 
 	// Functionality to upload it somewhere (NOT IMPLEMENTED YET)
@@ -84,14 +158,14 @@ func CreateResume(context *gin.Context) {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "File size exceeds the limit (5MB)",
 		})
-		return
+		return nil, "", false
 	}
 
 	if !strings.HasSuffix(strings.ToLower(header.Filename), ".pdf") {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "Only PDF files are allowed",
 		})
-		return
+		return nil, "", false
 	}
 
 	file, err := header.Open()
@@ -99,7 +173,7 @@ func CreateResume(context *gin.Context) {
 		context.JSON(http.StatusInternalServerError, gin.H{
 			"message": "Error opening file",
 		})
-		return
+		return nil, "", false
 	}
 	defer file.Close()
 
@@ -108,44 +182,18 @@ func CreateResume(context *gin.Context) {
 		context.JSON(http.StatusBadRequest, gin.H{
 			"message": "Job description is required",
 		})
-		return
+		return nil, "", false
 	}
 
-	if jobDescription == "" {
-		context.JSON(http.StatusBadRequest, gin.H{
-			"message": "Job description is required",
-		})
-		return
-	}
-
-	result, err := internal.ResumeAnalyzer(file, header, jobDescription)
+	resume, err := io.ReadAll(file)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Error analyzing resume: " + err.Error(),
+			"message": "Error reading file",
 		})
-		return
+		return nil, "", false
 	}
 
-	var resume models.Resume
-	resume.UserId = userId
-	resume.Title = result.Title
-	resume.OverallScore = result.OverallScore
-	resume.AnalysisSummary = result.AnalysisSummary
-	resume.ImprovementSuggestions = result.ImprovementSuggestions
-	resume.Metrics = result.Metrics
-
-	err = resume.Save()
-	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
-		return
-	}
-
-	context.JSON(http.StatusCreated, gin.H{
-		"message": "Resume analyzed successfully",
-		"data":    resume,
-	})
+	return resume, jobDescription, true
 }
 
 func DeleteResume(context *gin.Context) {
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"prepai.app/internal"
+)
+
+// Switches the response to server-sent events and returns the function the
+// generators use to push partial results. Once called, errors can only be
+// reported with streamError since the status is already sent.
+func startStream(context *gin.Context) func(internal.StreamEvent) {
+	context.Header("Content-Type", "text/event-stream")
+	context.Header("Cache-Control", "no-cache")
+	context.Header("Connection", "keep-alive")
+	// Keeps nginx from buffering the events
+	context.Header("X-Accel-Buffering", "no")
+	context.Status(http.StatusOK)
+	context.Writer.Flush()
+
+	return func(event internal.StreamEvent) {
+		context.SSEvent(event.Type, event)
+		context.Writer.Flush()
+	}
+}
+
+func streamError(context *gin.Context, status int, message string) {
+	context.SSEvent("error", gin.H{
+		"status":  status,
+		"message": message,
+	})
+	context.Writer.Flush()
+}
+
+// The last event of every stream, it carries the saved document.
+func streamDone(context *gin.Context, message string, data any) {
+	context.SSEvent("done", gin.H{
+		"message": message,
+		"data":    data,
+	})
+	context.Writer.Flush()
+}
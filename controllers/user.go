@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/internal"
 	"prepai.app/models"
 )
 
@@ -41,6 +42,29 @@ func GetUser(context *gin.Context) {
 	})
 }
 
+func GetUserUsage(context *gin.Context) {
+	userId, err := GetUserId(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	usage, err := models.GetUserUsage(userId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to get usage.",
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "Usage fetched successfully.",
+		"usage":   usage,
+	})
+}
+
 func UpdateUser(context *gin.Context) {
 	// Logic to get and validate bsonObjectID (needs extra step)
 	userIdInterface, exists := context.Get("userId")
@@ -67,6 +91,15 @@ func UpdateUser(context *gin.Context) {
 		return
 	}
 
+	if updatedUser.Language != "" {
+		language, ok := internal.ParseLanguage(updatedUser.Language)
+		if !ok {
+			unsupportedLanguage(context)
+			return
+		}
+		updatedUser.Language = language
+	}
+
 	updatedUser.Id = userId
 
 	err = updatedUser.Update()
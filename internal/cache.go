@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"prepai.app/providers"
+)
+
+// Cache stores validated responses by a hash of the request. A feature is
+// only cached when its TTL is positive.
+type Cache interface {
+	TTL(feature string) time.Duration
+	Get(ctx context.Context, key string) (string, bool)
+	Set(ctx context.Context, key string, feature string, text string, ttl time.Duration)
+}
+
+type bypassCacheKey struct{}
+
+// Generations made with this context always call the model and are not
+// stored, used when the user explicitly asks for new content.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+// Returns an empty key when the request must not use the cache. The rendered
+// prompt already reflects the template version and the inputs, so hashing it
+// with the model covers everything that changes the response.
+func (generator *Generator) cacheKey(ctx context.Context, request providers.Request) (string, time.Duration) {
+	if generator.Cache == nil || ctx.Value(bypassCacheKey{}) != nil {
+		return "", 0
+	}
+
+	ttl := generator.Cache.TTL(request.Feature)
+	if ttl <= 0 {
+		return "", 0
+	}
+
+	type part struct {
+		Text     string `json:"text,omitempty"`
+		MIMEType string `json:"mime_type,omitempty"`
+		Data     string `json:"data,omitempty"`
+	}
+	parts := make([]part, len(request.Parts))
+	for i, requestPart := range request.Parts {
+		parts[i] = part{Text: requestPart.Text, MIMEType: requestPart.MIMEType}
+		if requestPart.Data != nil {
+			sum := sha256.Sum256(requestPart.Data)
+			parts[i].Data = hex.EncodeToString(sum[:])
+		}
+	}
+
+	data, err := json.Marshal(struct {
+		Feature string `json:"feature"`
+		Model   string `json:"model"`
+		System  string `json:"system"`
+		Parts   []part `json:"parts"`
+	}{
+		Feature: request.Feature,
+		Model:   providers.ModelFor(generator.provider, request.Feature),
+		System:  request.System,
+		Parts:   parts,
+	})
+	if err != nil {
+		return "", 0
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), ttl
+}
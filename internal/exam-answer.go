@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Answer of a question the user did not answer, older clients send it to
+// clear a saved answer.
+const Unanswered = -1
+
+// Short answers longer than this are not accepted.
+const maxShortAnswer = 2000
+
+// Answer is what the user answered to a question: the option index for
+// true-false and multiple-choice, the chosen option indices for
+// multiple-select, the option indices in the chosen order for ordering and
+// the text for short-answer. It is encoded as that value alone, so single
+// choice answers stay plain numbers, and null when there is none.
+type Answer struct {
+	Choice  *int64
+	Choices []int64
+	Text    *string
+}
+
+func ChoiceAnswer(choice int64) Answer {
+	return Answer{Choice: &choice}
+}
+
+// What a question left unanswered is recorded with, single choice questions
+// keep using Unanswered.
+func (question ExamQuestion) NoAnswer() Answer {
+	if question.singleChoice() {
+		return ChoiceAnswer(Unanswered)
+	}
+
+	return Answer{}
+}
+
+func (answer Answer) Empty() bool {
+	switch {
+	case answer.Choices != nil:
+		return false
+	case answer.Text != nil:
+		return strings.TrimSpace(*answer.Text) == ""
+	case answer.Choice != nil:
+		return *answer.Choice == Unanswered
+	default:
+		return true
+	}
+}
+
+func (answer Answer) value() any {
+	switch {
+	case answer.Choices != nil:
+		return answer.Choices
+	case answer.Text != nil:
+		return *answer.Text
+	case answer.Choice != nil:
+		return *answer.Choice
+	default:
+		return nil
+	}
+}
+
+func (answer Answer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(answer.value())
+}
+
+func (answer *Answer) UnmarshalJSON(data []byte) error {
+	*answer = Answer{}
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case bytes.HasPrefix(data, []byte("[")):
+		answer.Choices = []int64{}
+		return json.Unmarshal(data, &answer.Choices)
+	case bytes.HasPrefix(data, []byte(`"`)):
+		answer.Text = new(string)
+		return json.Unmarshal(data, answer.Text)
+	default:
+		answer.Choice = new(int64)
+		return json.Unmarshal(data, answer.Choice)
+	}
+}
+
+func (answer Answer) MarshalBSONValue() (byte, []byte, error) {
+	value := answer.value()
+	if value == nil {
+		return byte(bson.TypeNull), nil, nil
+	}
+
+	typ, data, err := bson.MarshalValue(value)
+	return byte(typ), data, err
+}
+
+func (answer *Answer) UnmarshalBSONValue(typ byte, data []byte) error {
+	*answer = Answer{}
+	value := bson.RawValue{Type: bson.Type(typ), Value: data}
+
+	switch value.Type {
+	case bson.TypeNull, bson.TypeUndefined:
+		return nil
+	case bson.TypeArray:
+		answer.Choices = []int64{}
+		return value.Unmarshal(&answer.Choices)
+	case bson.TypeString:
+		text := value.StringValue()
+		answer.Text = &text
+		return nil
+	case bson.TypeInt32, bson.TypeInt64, bson.TypeDouble:
+		answer.Choice = new(int64)
+		return value.Unmarshal(answer.Choice)
+	default:
+		return errors.New("answer must be a number, an array or a string")
+	}
+}
+
+// Whether the answer has the form the question asks for and picks options
+// it has. Empty answers are valid, the question is left unanswered.
+func (question ExamQuestion) Accepts(answer Answer) bool {
+	if answer.Empty() {
+		return true
+	}
+
+	options := int64(len(question.Options))
+	switch question.kind() {
+	case MultipleSelect, Ordering:
+		if answer.Choices == nil {
+			return false
+		}
+		if question.kind() == Ordering && int64(len(answer.Choices)) != options {
+			return false
+		}
+		seen := make(map[int64]bool)
+		for _, choice := range answer.Choices {
+			if choice < 0 || choice >= options || seen[choice] {
+				return false
+			}
+			seen[choice] = true
+		}
+		return true
+	case ShortAnswer:
+		return answer.Text != nil && len(*answer.Text) <= maxShortAnswer
+	default:
+		return answer.Choice != nil && *answer.Choice >= 0 && *answer.Choice < options
+	}
+}
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func textAnswer(text string) Answer {
+	return Answer{Text: &text}
+}
+
+func TestAnswerJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer Answer
+		json   string
+	}{
+		{"number", ChoiceAnswer(2), `2`},
+		{"unanswered", ChoiceAnswer(Unanswered), `-1`},
+		{"array", Answer{Choices: []int64{3, 0, 1}}, `[3,0,1]`},
+		{"empty array", Answer{Choices: []int64{}}, `[]`},
+		{"string", textAnswer("a goroutine"), `"a goroutine"`},
+		{"null", Answer{}, `null`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(test.answer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.json {
+				t.Fatalf("encoded %s, want %s", data, test.json)
+			}
+
+			var decoded Answer
+			err = json.Unmarshal(data, &decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, test.answer) {
+				t.Fatalf("decoded %+v, want %+v", decoded, test.answer)
+			}
+		})
+	}
+}
+
+func TestAnswerBSON(t *testing.T) {
+	type document struct {
+		Answer Answer `bson:"answer"`
+	}
+
+	tests := []struct {
+		name   string
+		answer Answer
+		typ    bson.Type
+	}{
+		{"number", ChoiceAnswer(2), bson.TypeInt64},
+		{"unanswered", ChoiceAnswer(Unanswered), bson.TypeInt64},
+		{"array", Answer{Choices: []int64{3, 0, 1}}, bson.TypeArray},
+		{"string", textAnswer("a goroutine"), bson.TypeString},
+		{"null", Answer{}, bson.TypeNull},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := bson.Marshal(document{Answer: test.answer})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			typ := bson.Raw(data).Lookup("answer").Type
+			if typ != test.typ {
+				t.Fatalf("encoded as %v, want %v", typ, test.typ)
+			}
+
+			var decoded document
+			err = bson.Unmarshal(data, &decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded.Answer, test.answer) {
+				t.Fatalf("decoded %+v, want %+v", decoded.Answer, test.answer)
+			}
+		})
+	}
+}
+
+// Attempts stored before answers had a type saved them as plain numbers.
+func TestAnswerBSONLegacy(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  Answer
+	}{
+		{"int64", int64(1), ChoiceAnswer(1)},
+		{"int32", int32(3), ChoiceAnswer(3)},
+		{"double", float64(0), ChoiceAnswer(0)},
+		{"unanswered", int64(Unanswered), ChoiceAnswer(Unanswered)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := bson.Marshal(bson.D{{Key: "answer", Value: test.value}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded struct {
+				Answer Answer `bson:"answer"`
+			}
+			err = bson.Unmarshal(data, &decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded.Answer, test.want) {
+				t.Fatalf("decoded %+v, want %+v", decoded.Answer, test.want)
+			}
+		})
+	}
+}
+
+func TestAnswerBSONInvalid(t *testing.T) {
+	data, err := bson.Marshal(bson.D{{Key: "answer", Value: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Answer Answer `bson:"answer"`
+	}
+	if bson.Unmarshal(data, &decoded) == nil {
+		t.Fatal("decoded a boolean answer")
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	options := []string{"a", "b", "c", "d"}
+	single := ExamQuestion{Type: MultipleChoice, Options: options}
+	untyped := ExamQuestion{Options: options}
+	trueFalse := ExamQuestion{Type: TrueFalse, Options: []string{"True", "False"}}
+	multiple := ExamQuestion{Type: MultipleSelect, Options: options}
+	ordering := ExamQuestion{Type: Ordering, Options: options}
+	short := ExamQuestion{Type: ShortAnswer}
+
+	long := make([]byte, maxShortAnswer+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+
+	tests := []struct {
+		name     string
+		question ExamQuestion
+		answer   Answer
+		want     bool
+	}{
+		{"single first", single, ChoiceAnswer(0), true},
+		{"single last", single, ChoiceAnswer(3), true},
+		{"single past last", single, ChoiceAnswer(4), false},
+		{"single negative", single, ChoiceAnswer(-2), false},
+		{"single unanswered", single, ChoiceAnswer(Unanswered), true},
+		{"single null", single, Answer{}, true},
+		{"single array", single, Answer{Choices: []int64{0}}, false},
+		{"single text", single, textAnswer("a"), false},
+		{"untyped", untyped, ChoiceAnswer(3), true},
+		{"true-false", trueFalse, ChoiceAnswer(1), true},
+		{"true-false past last", trueFalse, ChoiceAnswer(2), false},
+		{"multiple", multiple, Answer{Choices: []int64{0, 3}}, true},
+		{"multiple empty", multiple, Answer{Choices: []int64{}}, true},
+		{"multiple past last", multiple, Answer{Choices: []int64{0, 4}}, false},
+		{"multiple negative", multiple, Answer{Choices: []int64{-1}}, false},
+		{"multiple repeated", multiple, Answer{Choices: []int64{1, 1}}, false},
+		{"multiple number", multiple, ChoiceAnswer(1), false},
+		{"ordering", ordering, Answer{Choices: []int64{3, 1, 0, 2}}, true},
+		{"ordering short", ordering, Answer{Choices: []int64{3, 1, 0}}, false},
+		{"ordering long", ordering, Answer{Choices: []int64{3, 1, 0, 2, 4}}, false},
+		{"ordering repeated", ordering, Answer{Choices: []int64{3, 1, 1, 2}}, false},
+		{"ordering null", ordering, Answer{}, true},
+		{"short", short, textAnswer("a goroutine"), true},
+		{"short blank", short, textAnswer("  "), true},
+		{"short at limit", short, textAnswer(string(long[1:])), true},
+		{"short too long", short, textAnswer(string(long)), false},
+		{"short number", short, ChoiceAnswer(0), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.question.Accepts(test.answer)
+			if got != test.want {
+				t.Fatalf("Accepts(%+v) = %v, want %v", test.answer, got, test.want)
+			}
+		})
+	}
+}
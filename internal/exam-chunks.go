@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/sync/errgroup"
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+const (
+	// How many more calls are made for the questions missing once duplicates
+	// are dropped
+	examTopUps = 2
+	// Questions sharing at least this part of their words ask the same thing
+	duplicateSimilarity = 0.8
+)
+
+type ExamOutline struct {
+	Title string   `json:"title"`
+	Areas []string `json:"areas"`
+}
+
+type ExamChunk struct {
+	Questions []ExamQuestion `json:"questions"`
+}
+
+// Large exams do not fit in one response. They are split into sub-areas of
+// the subject whose questions are generated concurrently, merged without
+// near-duplicates and topped up when some were dropped. Questions are kept
+// in the order their chunk finished, emit (if not nil) receives each one as
+// it is merged, already shuffled with seed. The Ref is the one of the chunk
+// prompt.
+func (generator *Generator) generateExamInChunks(ctx context.Context, subject string, difficulty string, examType string, seed int64, emit func(StreamEvent)) (ExamResponse, error) {
+	expected := ExpectedExamQuestions(difficulty)
+	chunks := (expected + generator.ExamChunkSize - 1) / generator.ExamChunkSize
+
+	outline, err := generator.examOutline(ctx, subject, difficulty, chunks)
+	if err != nil {
+		return ExamResponse{}, err
+	}
+
+	exam := ExamResponse{Title: outline.Title, Seed: seed}
+	if emit != nil {
+		title, _ := json.Marshal(outline.Title)
+		emit(StreamEvent{Type: StreamField, Key: "title", Index: -1, Data: json.RawMessage(title)})
+	}
+
+	var mutex sync.Mutex
+	merge := func(questions []ExamQuestion, ref prompts.Ref) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		exam.Prompt = ref
+		for _, question := range questions {
+			if len(exam.Questions) == expected || isDuplicateQuestion(exam.Questions, question) {
+				continue
+			}
+			question = arrangeQuestion(question, examType, seed, len(exam.Questions))
+			exam.Questions = append(exam.Questions, question)
+			if emit != nil {
+				emit(StreamEvent{Type: StreamQuestion, Index: len(exam.Questions) - 1, Data: ExamQuestionPreview{
+					Type:     question.Type,
+					Question: question.Question,
+					Options:  question.Options,
+				}})
+			}
+		}
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for i, area := range outline.Areas {
+		count := expected / chunks
+		if i < expected%chunks {
+			count++
+		}
+
+		group.Go(func() error {
+			questions, ref, err := generator.examChunk(groupCtx, prompts.ExamChunkInput{
+				Subject:    subject,
+				Difficulty: difficulty,
+				Type:       examType,
+				Area:       area,
+				Count:      count,
+			})
+			if err != nil {
+				return err
+			}
+
+			merge(questions, ref)
+			return nil
+		})
+	}
+
+	err = group.Wait()
+	if err != nil {
+		return ExamResponse{}, err
+	}
+
+	for round := 0; round < examTopUps && len(exam.Questions) < expected; round++ {
+		avoid := make([]string, len(exam.Questions))
+		for i, question := range exam.Questions {
+			avoid[i] = question.Question
+		}
+
+		questions, ref, err := generator.examChunk(ctx, prompts.ExamChunkInput{
+			Subject:    subject,
+			Difficulty: difficulty,
+			Type:       examType,
+			Count:      expected - len(exam.Questions),
+			Avoid:      avoid,
+		})
+		if err != nil {
+			return ExamResponse{}, err
+		}
+
+		merge(questions, ref)
+	}
+
+	violations := exam.Validate(examType, difficulty)
+	if len(violations) > 0 {
+		return ExamResponse{}, &ValidationError{
+			Feature:    providers.FeatureExam,
+			Violations: violations,
+			Attempts:   examTopUps + 1,
+		}
+	}
+
+	exam.assignIds()
+	return exam, nil
+}
+
+func (generator *Generator) examOutline(ctx context.Context, subject string, difficulty string, areas int) (ExamOutline, error) {
+	request, _, err := generator.request(ctx, providers.FeatureExam, prompts.ExamOutline, prompts.ExamOutlineInput{
+		Subject:    subject,
+		Difficulty: difficulty,
+		Areas:      areas,
+	})
+	if err != nil {
+		return ExamOutline{}, err
+	}
+
+	var outline ExamOutline
+
+	err = generator.generate(ctx, request, &outline, func() []string {
+		return outline.Validate(areas)
+	})
+	if err != nil {
+		return ExamOutline{}, err
+	}
+
+	return outline, nil
+}
+
+func (generator *Generator) examChunk(ctx context.Context, input prompts.ExamChunkInput) ([]ExamQuestion, prompts.Ref, error) {
+	request, ref, err := generator.request(ctx, providers.FeatureExam, prompts.ExamChunk, input)
+	if err != nil {
+		return nil, prompts.Ref{}, err
+	}
+
+	var chunk ExamChunk
+
+	err = generator.generate(ctx, request, &chunk, func() []string {
+		return chunk.Validate(input.Count, input.Type)
+	})
+	if err != nil {
+		return nil, prompts.Ref{}, err
+	}
+
+	return chunk.Questions, ref, nil
+}
+
+// Compares the words of the question with those already in the exam, so
+// rewordings such as a different article or punctuation still match.
+func isDuplicateQuestion(questions []ExamQuestion, question ExamQuestion) bool {
+	words := questionWords(question.Question)
+	for _, other := range questions {
+		if similarity(words, questionWords(other.Question)) >= duplicateSimilarity {
+			return true
+		}
+	}
+
+	return false
+}
+
+func questionWords(question string) map[string]bool {
+	fields := strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		words[field] = true
+	}
+
+	return words
+}
+
+// Jaccard index of two sets of words.
+func similarity(a map[string]bool, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func (outline ExamOutline) Validate(areas int) []string {
+	var violations []string
+
+	if strings.TrimSpace(outline.Title) == "" {
+		violations = append(violations, "title is empty")
+	}
+	if len(outline.Areas) != areas {
+		violations = append(violations, fmt.Sprintf("areas needs exactly %v entries, got %v", areas, len(outline.Areas)))
+	}
+
+	seen := make(map[string]bool)
+	for i, area := range outline.Areas {
+		area = strings.ToLower(strings.TrimSpace(area))
+		if area == "" {
+			violations = append(violations, fmt.Sprintf("areas[%v] is empty", i))
+		} else if seen[area] {
+			violations = append(violations, fmt.Sprintf("areas[%v] is duplicated", i))
+		}
+		seen[area] = true
+	}
+
+	return violations
+}
+
+func (chunk ExamChunk) Validate(count int, examType string) []string {
+	var violations []string
+
+	if len(chunk.Questions) != count {
+		violations = append(violations, fmt.Sprintf("questions needs exactly %v entries, got %v", count, len(chunk.Questions)))
+	}
+
+	for i, question := range chunk.Questions {
+		violations = append(violations, question.Validate(i, examType)...)
+	}
+
+	return violations
+}
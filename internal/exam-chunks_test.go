@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+var chunkPrompt = regexp.MustCompile(`Generate (\d+) .*exam questions`)
+var chunkArea = regexp.MustCompile(`sub-area of the topic: (.+)\.`)
+
+// Words that tell apart the questions of a chunk, numbers would be too
+// similar to count as different questions.
+var facts = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel"}
+
+// Answers the outline and chunk requests of a chunked exam. Questions are
+// named after their area so every chunk writes different ones, repeat lists
+// the areas whose questions copy those of the first area.
+type chunkedExamModel struct {
+	repeat map[string]bool
+
+	mutex    sync.Mutex
+	topUps   int
+	requests []string
+}
+
+func (model *chunkedExamModel) respond(request providers.Request) (string, error) {
+	prompt := request.Parts[len(request.Parts)-1].Text
+
+	model.mutex.Lock()
+	model.requests = append(model.requests, prompt)
+	model.mutex.Unlock()
+
+	match := chunkPrompt.FindStringSubmatch(prompt)
+	if match == nil {
+		return `{"title": "Go concurrency", "areas": ["Goroutines", "Channels", "Sync"]}`, nil
+	}
+	count, _ := strconv.Atoi(match[1])
+
+	var area string
+	if match := chunkArea.FindStringSubmatch(prompt); match != nil {
+		area = match[1]
+	} else {
+		model.mutex.Lock()
+		model.topUps++
+		area = fmt.Sprintf("Extra%v", model.topUps)
+		model.mutex.Unlock()
+	}
+
+	name := area
+	if model.repeat[area] {
+		name = "Goroutines"
+	}
+
+	var chunk ExamChunk
+	for i := range count {
+		chunk.Questions = append(chunk.Questions, ExamQuestion{
+			Question:    fmt.Sprintf("What is the %v %v fact?", name, facts[i]),
+			Options:     []string{"First", "Second", "Third", "Fourth"},
+			Correct:     int64(i % 4),
+			Explanation: "Because the spec says so.",
+		})
+	}
+
+	data, err := json.Marshal(chunk)
+	return string(data), err
+}
+
+func (model *chunkedExamModel) chunkRequests() []string {
+	model.mutex.Lock()
+	defer model.mutex.Unlock()
+
+	var chunks []string
+	for _, prompt := range model.requests {
+		if chunkPrompt.MatchString(prompt) {
+			chunks = append(chunks, prompt)
+		}
+	}
+
+	return chunks
+}
+
+func TestGenerateExamInChunks(t *testing.T) {
+	model := &chunkedExamModel{}
+	fake := providers.NewFake()
+	fake.Handler = model.respond
+
+	generator := NewGenerator(fake)
+	generator.ExamChunkSize = 8
+
+	exam, err := generator.GenerateExam(context.Background(), "Go concurrency", "hard", "multiple-choice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exam.Title != "Go concurrency" || len(exam.Questions) != 20 {
+		t.Fatalf("got %q with %v questions, want 20", exam.Title, len(exam.Questions))
+	}
+
+	// One outline and a chunk per area, splitting the 20 questions evenly
+	chunks := model.chunkRequests()
+	if len(model.requests) != 4 || len(chunks) != 3 {
+		t.Fatalf("made %v requests with %v chunks, want an outline and 3 chunks", len(model.requests), len(chunks))
+	}
+	counts := 0
+	for _, prompt := range chunks {
+		count, _ := strconv.Atoi(chunkPrompt.FindStringSubmatch(prompt)[1])
+		if count < 6 || count > 7 {
+			t.Fatalf("chunk asks for %v questions, want 6 or 7", count)
+		}
+		counts += count
+	}
+	if counts != 20 {
+		t.Fatalf("chunks ask for %v questions, want 20", counts)
+	}
+}
+
+// Only the hard exams are chunked by default, a medium exam is still asked
+// for in one call.
+func TestGenerateMediumExamInOneCall(t *testing.T) {
+	fake := providers.NewFake(marshal(t, multipleChoiceExam(15)))
+	generator := NewGenerator(fake)
+
+	exam, err := generator.GenerateExam(context.Background(), "Go concurrency", "medium", "multiple-choice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exam.Questions) != 15 {
+		t.Fatalf("got %v questions, want 15", len(exam.Questions))
+	}
+	if len(fake.Requests()) != 1 || exam.Prompt.Name != prompts.Exam {
+		t.Fatalf("made %v requests with the %q prompt, want a single %q call", len(fake.Requests()), exam.Prompt.Name, prompts.Exam)
+	}
+}
+
+// Questions repeated across chunks are dropped and asked for again, telling
+// the model which ones the exam already has.
+func TestGenerateExamInChunksTopsUpDuplicates(t *testing.T) {
+	model := &chunkedExamModel{repeat: map[string]bool{"Channels": true}}
+	fake := providers.NewFake()
+	fake.Handler = model.respond
+
+	generator := NewGenerator(fake)
+	generator.ExamChunkSize = 8
+
+	exam, err := generator.GenerateExam(context.Background(), "Go concurrency", "hard", "multiple-choice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exam.Questions) != 20 {
+		t.Fatalf("got %v questions, want 20", len(exam.Questions))
+	}
+	for i, question := range exam.Questions {
+		if isDuplicateQuestion(exam.Questions[:i], question) {
+			t.Fatalf("questions[%v] %q is repeated", i, question.Question)
+		}
+	}
+
+	chunks := model.chunkRequests()
+	topUp := chunks[len(chunks)-1]
+	if len(chunks) != 4 || chunkArea.MatchString(topUp) || !strings.Contains(topUp, "do not repeat them") {
+		t.Fatalf("the missing questions were not asked for again:\n%v", topUp)
+	}
+}
+
+func TestIsDuplicateQuestion(t *testing.T) {
+	questions := []ExamQuestion{{Question: "What does the go keyword do before a function call?"}}
+
+	tests := []struct {
+		question string
+		want     bool
+	}{
+		{"What does the go keyword do before a function call?", true},
+		{"what does the GO keyword do, before a function call", true},
+		{"What does the defer keyword do before a function returns?", false},
+		{"Which type waits for a group of goroutines?", false},
+	}
+
+	for _, test := range tests {
+		got := isDuplicateQuestion(questions, ExamQuestion{Question: test.question})
+		if got != test.want {
+			t.Errorf("isDuplicateQuestion(%q) = %v, want %v", test.question, got, test.want)
+		}
+	}
+}
+
+// An experiment on the prompt an exam is generated with is recorded in the
+// Ref of the exam, whether it takes one call or is chunked.
+func TestGenerateExamRecordsExperiment(t *testing.T) {
+	tests := []struct {
+		prompt     string
+		difficulty string
+		chunkSize  int
+	}{
+		{prompts.Exam, "medium", 15},
+		{prompts.ExamChunk, "hard", 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.prompt, func(t *testing.T) {
+			dir := t.TempDir()
+			experiments := []prompts.Experiment{{
+				Name:     "exam-wording",
+				Prompt:   test.prompt,
+				Variants: []prompts.Variant{{Version: 1, Weight: 1}, {Version: 2, Weight: 1}},
+			}}
+			err := os.WriteFile(filepath.Join(dir, "experiments.json"), []byte(marshal(t, experiments)), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			registry, err := prompts.Load(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			model := &chunkedExamModel{}
+			fake := providers.NewFake()
+			fake.Handler = func(request providers.Request) (string, error) {
+				if test.prompt == prompts.Exam {
+					return marshal(t, multipleChoiceExam(15)), nil
+				}
+				return model.respond(request)
+			}
+
+			generator := NewGenerator(fake)
+			generator.Prompts = registry
+			generator.ExamChunkSize = test.chunkSize
+
+			userId := bson.NewObjectID()
+			ctx := WithUserId(context.Background(), userId)
+			exam, err := generator.GenerateExam(ctx, "Go concurrency", test.difficulty, "multiple-choice")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := prompts.Ref{
+				Name:       test.prompt,
+				Version:    experiments[0].Assign(userId.Hex()).Version,
+				Experiment: "exam-wording",
+			}
+			if exam.Prompt != want || want.Version == registry.Version(test.prompt) {
+				t.Fatalf("exam has prompt %+v, want %+v", exam.Prompt, want)
+			}
+		})
+	}
+}
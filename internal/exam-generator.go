@@ -1,66 +1,152 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 
-	"google.golang.org/genai"
-	"prepai.app/configs"
+	"prepai.app/prompts"
+	"prepai.app/providers"
 )
 
+// Exam types, every question of an exam has the type of the exam.
+const (
+	TrueFalse      = "true-false"
+	MultipleChoice = "multiple-choice"
+	MultipleSelect = "multiple-select"
+	ShortAnswer    = "short-answer"
+	Ordering       = "ordering"
+)
+
+var ExamTypes = []string{TrueFalse, MultipleChoice, MultipleSelect, ShortAnswer, Ordering}
+
+// Which answer key is set depends on the type: Correct for true-false and
+// multiple-choice, CorrectOptions for multiple-select, CorrectOrder (the
+// option indices in the right order) for ordering and ReferenceAnswer for
+// short-answer, which has no options.
 type ExamQuestion struct {
-	Question    string   `json:"question"`
-	Options     []string `json:"options"`
-	Correct     int64    `json:"correct"`
-	Explanation string   `json:"explanation"`
+	Id              string   `json:"id"`
+	Type            string   `json:"type,omitempty"`
+	Question        string   `json:"question"`
+	Options         []string `json:"options"`
+	Correct         int64    `json:"correct"`
+	CorrectOptions  []int64  `json:"correct_options,omitempty"`
+	CorrectOrder    []int64  `json:"correct_order,omitempty"`
+	ReferenceAnswer string   `json:"reference_answer,omitempty"`
+	Explanation     string   `json:"explanation"`
+}
+
+// Questions saved before they had a type are all single choice.
+func (question ExamQuestion) kind() string {
+	if question.Type == "" {
+		return MultipleChoice
+	}
+
+	return question.Type
+}
+
+// Whether the question has one correct option, given by Correct.
+func (question ExamQuestion) singleChoice() bool {
+	return question.kind() == TrueFalse || question.kind() == MultipleChoice
 }
 
 type ExamResponse struct {
 	Title     string         `json:"title"`
 	Questions []ExamQuestion `json:"questions"`
+	Prompt    prompts.Ref    `json:"-"`
+	// Seed the options were shuffled with
+	Seed int64 `json:"-"`
 }
 
-func GenerateExam(subject string, difficulty string, examType string) (ExamResponse, error) {
-	prompt := fmt.Sprintf(`
-		Generate a %v exam on the topic %v, with %v difficulty.
-		- If the exam type is multiple choice, generate 4 options per question.
-		- If the exam type is true/false, generate only 2 options: "True" and "False".
-
-		Based on the difficulty level:
-		- "easy": generate 10 questions
-		- "medium": generate 15 questions
-		- "hard": generate 20 questions
-
-		For each question:
-		- Randomly shuffle the answer options so the correct one is not always in the same index.
-		- Provide the correct answer's index (0-based).
-		- Make sure the correct answer value matches the position of the correct option after shuffling.
-		- Provide an explanation (Explain in 3-4 lines why the correct answer is correct)
-		- Format the output in the following JSON schema:
-		{
-			"title": string,
-			"questions": [
-				{
-				"question": string,
-				"options": [string],
-				"correct": int64
-				"explanation": string
-				}
-			]
-		}
-`, examType, subject, difficulty)
+// What the candidate sees of a question while the exam is still being generated
+type ExamQuestionPreview struct {
+	Type     string   `json:"type"`
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+}
+
+func (generator *Generator) examRequest(ctx context.Context, subject string, difficulty string, examType string) (providers.Request, prompts.Ref, error) {
+	return generator.request(ctx, providers.FeatureExam, prompts.Exam, prompts.ExamInput{
+		Subject:    subject,
+		Difficulty: difficulty,
+		Type:       examType,
+	})
+}
+
+// Whether the exam has too many questions to ask for them in one call.
+func (generator *Generator) chunked(difficulty string) bool {
+	return generator.ExamChunkSize > 0 && ExpectedExamQuestions(difficulty) > generator.ExamChunkSize
+}
+
+func (generator *Generator) GenerateExam(ctx context.Context, subject string, difficulty string, examType string) (ExamResponse, error) {
+	if generator.chunked(difficulty) {
+		return generator.generateExamInChunks(ctx, subject, difficulty, examType, newSeed(), nil)
+	}
+
+	request, ref, err := generator.examRequest(ctx, subject, difficulty, examType)
+	if err != nil {
+		return ExamResponse{}, err
+	}
+
+	var questions ExamResponse
+
+	err = generator.generate(ctx, request, &questions, func() []string {
+		return questions.Validate(examType, difficulty)
+	})
+	if err != nil {
+		return ExamResponse{}, err
+	}
 
-	result, err := configs.Gemini(genai.Text(prompt))
+	questions.arrange(examType)
+	questions.assignIds()
+	questions.Prompt = ref
+	return questions, nil
+}
+
+// Streams each question as soon as the model finishes writing it. Correct
+// answers and explanations are left out until the exam is attempted.
+func (generator *Generator) StreamExam(ctx context.Context, subject string, difficulty string, examType string, emit func(StreamEvent)) (ExamResponse, error) {
+	if generator.chunked(difficulty) {
+		return generator.generateExamInChunks(ctx, subject, difficulty, examType, newSeed(), emit)
+	}
+
+	request, ref, err := generator.examRequest(ctx, subject, difficulty, examType)
 	if err != nil {
 		return ExamResponse{}, err
 	}
 
 	var questions ExamResponse
+	seed := newSeed()
 
-	err = json.Unmarshal([]byte(result), &questions)
+	onValue := func(key string, index int, raw json.RawMessage) {
+		switch {
+		case key == "questions" && index >= 0:
+			var question ExamQuestion
+			if json.Unmarshal(raw, &question) == nil && len(question.Validate(index, examType)) == 0 {
+				// Shown in the order the exam will be saved with
+				question = arrangeQuestion(question, examType, seed, index)
+				emit(StreamEvent{Type: StreamQuestion, Index: index, Data: ExamQuestionPreview{
+					Type:     question.Type,
+					Question: question.Question,
+					Options:  question.Options,
+				}})
+			}
+		case key == "title" && index < 0:
+			emit(StreamEvent{Type: StreamField, Key: key, Index: index, Data: raw})
+		}
+	}
+
+	err = generator.generateStream(ctx, request, &questions, func() []string {
+		return questions.Validate(examType, difficulty)
+	}, onValue, retryEvent(emit))
 	if err != nil {
 		return ExamResponse{}, err
 	}
 
+	for i, question := range questions.Questions {
+		questions.Questions[i] = arrangeQuestion(question, examType, seed, i)
+	}
+	questions.assignIds()
+	questions.Seed = seed
+	questions.Prompt = ref
 	return questions, nil
 }
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"context"
+
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+// Grade of one answer. Credit goes from 0 (wrong or unanswered) to 1, only
+// short answers get feedback.
+type AnswerGrade struct {
+	Credit   float64
+	Feedback string
+}
+
+type ShortAnswerGrade struct {
+	Score    float64 `json:"score"`
+	Feedback string  `json:"feedback"`
+}
+
+type ShortAnswerGrades struct {
+	Grades []ShortAnswerGrade `json:"grades"`
+	Prompt prompts.Ref        `json:"-"`
+}
+
+// Grades the answers, keyed by question id, of every question in order.
+// Short answers are graded by the model against the reference answer in a
+// single call, the rest by their answer key.
+func (generator *Generator) GradeExam(ctx context.Context, questions []ExamQuestion, answers map[string]Answer) ([]AnswerGrade, error) {
+	grades := make([]AnswerGrade, len(questions))
+
+	var short []prompts.ShortAnswer
+	var indices []int
+	for i, question := range questions {
+		answer := answers[question.Id]
+		if answer.Empty() || !question.Accepts(answer) {
+			continue
+		}
+
+		if question.kind() == ShortAnswer {
+			short = append(short, prompts.ShortAnswer{
+				Question:        question.Question,
+				ReferenceAnswer: question.ReferenceAnswer,
+				Answer:          *answer.Text,
+			})
+			indices = append(indices, i)
+			continue
+		}
+
+		grades[i].Credit = question.Grade(answer)
+	}
+
+	if len(short) == 0 {
+		return grades, nil
+	}
+
+	results, err := generator.GradeShortAnswers(ctx, short)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range indices {
+		grades[i] = AnswerGrade{
+			Credit:   results.Grades[j].Score / 10,
+			Feedback: results.Grades[j].Feedback,
+		}
+	}
+
+	return grades, nil
+}
+
+// Credit of an answer graded by the answer key. Multiple-select answers get
+// a share for each correct option picked less one for each wrong one, and
+// ordering answers a share for each item in its place. Short answers need
+// the model and get none here.
+func (question ExamQuestion) Grade(answer Answer) float64 {
+	if answer.Empty() || !question.Accepts(answer) {
+		return 0
+	}
+
+	switch question.kind() {
+	case MultipleSelect:
+		correct := make(map[int64]bool, len(question.CorrectOptions))
+		for _, option := range question.CorrectOptions {
+			correct[option] = true
+		}
+
+		points := 0
+		for _, choice := range answer.Choices {
+			if correct[choice] {
+				points++
+			} else {
+				points--
+			}
+		}
+		if points <= 0 || len(correct) == 0 {
+			return 0
+		}
+		return float64(points) / float64(len(correct))
+	case Ordering:
+		placed := 0
+		for position, choice := range answer.Choices {
+			if position < len(question.CorrectOrder) && question.CorrectOrder[position] == choice {
+				placed++
+			}
+		}
+		if len(question.CorrectOrder) == 0 {
+			return 0
+		}
+		return float64(placed) / float64(len(question.CorrectOrder))
+	case ShortAnswer:
+		return 0
+	default:
+		if *answer.Choice == question.Correct {
+			return 1
+		}
+		return 0
+	}
+}
+
+func (generator *Generator) GradeShortAnswers(ctx context.Context, answers []prompts.ShortAnswer) (ShortAnswerGrades, error) {
+	request, ref, err := generator.request(ctx, providers.FeatureGrade, prompts.GradeAnswers, prompts.GradeAnswersInput{
+		Answers: answers,
+	})
+	if err != nil {
+		return ShortAnswerGrades{}, err
+	}
+
+	var grades ShortAnswerGrades
+
+	err = generator.generate(ctx, request, &grades, func() []string {
+		return grades.Validate(len(answers))
+	})
+	if err != nil {
+		return ShortAnswerGrades{}, err
+	}
+
+	grades.Prompt = ref
+	return grades, nil
+}
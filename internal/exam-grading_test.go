@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"prepai.app/providers"
+)
+
+func TestGrade(t *testing.T) {
+	options := []string{"a", "b", "c", "d", "e"}
+	single := ExamQuestion{Type: MultipleChoice, Options: options, Correct: 2}
+	multiple := ExamQuestion{Type: MultipleSelect, Options: options, CorrectOptions: []int64{0, 2, 4}}
+	ordering := ExamQuestion{Type: Ordering, Options: options[:4], CorrectOrder: []int64{2, 0, 3, 1}}
+	short := ExamQuestion{Type: ShortAnswer, ReferenceAnswer: "a goroutine"}
+
+	tests := []struct {
+		name     string
+		question ExamQuestion
+		answer   Answer
+		want     float64
+	}{
+		{"single correct", single, ChoiceAnswer(2), 1},
+		{"single wrong", single, ChoiceAnswer(1), 0},
+		{"single unanswered", single, ChoiceAnswer(Unanswered), 0},
+		{"single out of range", single, ChoiceAnswer(7), 0},
+		{"untyped correct", ExamQuestion{Options: options, Correct: 2}, ChoiceAnswer(2), 1},
+		{"multiple all correct", multiple, Answer{Choices: []int64{4, 0, 2}}, 1},
+		{"multiple some correct", multiple, Answer{Choices: []int64{0, 2}}, 2.0 / 3},
+		{"multiple one wrong", multiple, Answer{Choices: []int64{0, 2, 4, 1}}, 2.0 / 3},
+		{"multiple as many wrong", multiple, Answer{Choices: []int64{0, 1}}, 0},
+		{"multiple more wrong", multiple, Answer{Choices: []int64{0, 1, 3}}, 0},
+		{"multiple every option", multiple, Answer{Choices: []int64{0, 1, 2, 3, 4}}, 1.0 / 3},
+		{"multiple none picked", multiple, Answer{Choices: []int64{}}, 0},
+		{"multiple invalid", multiple, Answer{Choices: []int64{0, 0}}, 0},
+		{"ordering correct", ordering, Answer{Choices: []int64{2, 0, 3, 1}}, 1},
+		{"ordering half", ordering, Answer{Choices: []int64{2, 0, 1, 3}}, 0.5},
+		{"ordering one", ordering, Answer{Choices: []int64{2, 1, 0, 3}}, 0.25},
+		{"ordering none", ordering, Answer{Choices: []int64{0, 1, 2, 3}}, 0},
+		{"ordering incomplete", ordering, Answer{Choices: []int64{2, 0, 3}}, 0},
+		{"short", short, textAnswer("a goroutine"), 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.question.Grade(test.answer)
+			if math.Abs(got-test.want) > 1e-9 {
+				t.Fatalf("Grade(%+v) = %v, want %v", test.answer, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGradeExam(t *testing.T) {
+	questions := []ExamQuestion{
+		{Id: "single", Type: MultipleChoice, Question: "Q1", Options: []string{"a", "b", "c", "d"}, Correct: 1},
+		{Id: "short-right", Type: ShortAnswer, Question: "Q2", ReferenceAnswer: "a goroutine"},
+		{Id: "multiple", Type: MultipleSelect, Question: "Q3", Options: []string{"a", "b", "c", "d", "e"}, CorrectOptions: []int64{0, 1}},
+		{Id: "short-blank", Type: ShortAnswer, Question: "Q4", ReferenceAnswer: "a channel"},
+		{Id: "short-wrong", Type: ShortAnswer, Question: "Q5", ReferenceAnswer: "a mutex"},
+		{Id: "unanswered", Type: Ordering, Question: "Q6", Options: []string{"a", "b", "c"}, CorrectOrder: []int64{2, 1, 0}},
+	}
+	answers := map[string]Answer{
+		"single":      ChoiceAnswer(1),
+		"short-right": textAnswer("a lightweight thread"),
+		"multiple":    {Choices: []int64{0}},
+		"short-blank": textAnswer(" "),
+		"short-wrong": textAnswer("a lock"),
+	}
+
+	fake := providers.NewFake(`{"grades": [
+		{"score": 10, "feedback": "Right."},
+		{"score": 4, "feedback": "A lock is close but not the answer."}
+	]}`)
+	generator := NewGenerator(fake)
+
+	grades, err := generator.GradeExam(context.Background(), questions, answers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AnswerGrade{
+		{Credit: 1},
+		{Credit: 1, Feedback: "Right."},
+		{Credit: 0.5},
+		{},
+		{Credit: 0.4, Feedback: "A lock is close but not the answer."},
+		{},
+	}
+	if len(grades) != len(want) {
+		t.Fatalf("got %v grades, want %v", len(grades), len(want))
+	}
+	for i := range want {
+		if math.Abs(grades[i].Credit-want[i].Credit) > 1e-9 || grades[i].Feedback != want[i].Feedback {
+			t.Errorf("grades[%v] = %+v, want %+v", i, grades[i], want[i])
+		}
+	}
+
+	// Only the answered short answers are sent to the model, in one call
+	requests := fake.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("made %v requests, want 1", len(requests))
+	}
+	if requests[0].Feature != providers.FeatureGrade {
+		t.Fatalf("request feature %q, want %q", requests[0].Feature, providers.FeatureGrade)
+	}
+}
+
+func TestGradeExamWithoutShortAnswers(t *testing.T) {
+	questions := []ExamQuestion{
+		{Id: "first", Type: TrueFalse, Options: []string{"True", "False"}, Correct: 0},
+		{Id: "second", Type: TrueFalse, Options: []string{"True", "False"}, Correct: 1},
+	}
+	answers := map[string]Answer{"first": ChoiceAnswer(0), "second": ChoiceAnswer(0)}
+
+	fake := providers.NewFake()
+	grades, err := NewGenerator(fake).GradeExam(context.Background(), questions, answers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if grades[0].Credit != 1 || grades[1].Credit != 0 {
+		t.Fatalf("got grades %+v", grades)
+	}
+	if len(fake.Requests()) != 0 {
+		t.Fatal("graded answers by their key with the model")
+	}
+}
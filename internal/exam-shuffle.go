@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// True/false questions always show the options in this order.
+var trueFalseOptions = []string{"True", "False"}
+
+// Models cluster the correct answer in the same position whatever the prompt
+// asks, so the server puts the options in order itself. Each question is
+// shuffled with its own generator derived from the exam seed and its index,
+// the same seed always gives the same order. Ordering questions come with
+// their items in the right order, which is kept as the answer key.
+func arrangeQuestion(question ExamQuestion, examType string, seed int64, index int) ExamQuestion {
+	question.Type = examType
+
+	switch examType {
+	case TrueFalse:
+		correct := strings.ToLower(strings.TrimSpace(question.Options[question.Correct]))
+		question.Options = append([]string(nil), trueFalseOptions...)
+		question.Correct = 0
+		if correct == "false" {
+			question.Correct = 1
+		}
+		return question
+	case ShortAnswer:
+		return question
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(seed), uint64(index)))
+	order := rng.Perm(len(question.Options))
+
+	// Where each of the original options ended up
+	positions := make([]int64, len(question.Options))
+	options := make([]string, len(question.Options))
+	for position, from := range order {
+		options[position] = question.Options[from]
+		positions[from] = int64(position)
+	}
+	question.Options = options
+
+	switch examType {
+	case MultipleSelect:
+		correct := make([]int64, len(question.CorrectOptions))
+		for i, from := range question.CorrectOptions {
+			correct[i] = positions[from]
+		}
+		slices.Sort(correct)
+		question.CorrectOptions = correct
+	case Ordering:
+		question.CorrectOrder = positions
+	default:
+		question.Correct = positions[question.Correct]
+	}
+
+	return question
+}
+
+// Shuffles every question with a new seed, kept in the response so the order
+// can be reproduced.
+func (exam *ExamResponse) arrange(examType string) {
+	exam.Seed = newSeed()
+	for i, question := range exam.Questions {
+		exam.Questions[i] = arrangeQuestion(question, examType, exam.Seed, i)
+	}
+}
+
+func newSeed() int64 {
+	return rand.Int64()
+}
+
+// CorrectPositions counts how many questions have their correct answer at
+// each option index. Only questions with a single correct option count.
+func (exam ExamResponse) CorrectPositions() []int64 {
+	var positions []int64
+	for _, question := range exam.Questions {
+		if !question.singleChoice() {
+			continue
+		}
+		for int64(len(positions)) <= question.Correct {
+			positions = append(positions, 0)
+		}
+		positions[question.Correct]++
+	}
+
+	return positions
+}
@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+// Generator holds the model provider and prompts used by every content
+// generator.
+type Generator struct {
+	provider providers.Provider
+
+	Prompts *prompts.Registry
+
+	// How many times the model is asked to fix an invalid response
+	MaxRepairs int
+
+	// Exams with more questions are generated in chunks of this size, zero
+	// generates every exam in a single call
+	ExamChunkSize int
+
+	// Optional, meters and limits the tokens spent by each user
+	Usage UsageTracker
+
+	// Optional, reuses the responses of identical requests
+	Cache Cache
+}
+
+func NewGenerator(provider providers.Provider) *Generator {
+	return &Generator{provider: provider, Prompts: prompts.Embedded(), MaxRepairs: 2, ExamChunkSize: 15}
+}
+
+// Renders the named prompt into a request for feature, with the system
+// instructions and any files placed before the prompt. The user in ctx
+// decides the variant when the prompt has an experiment.
+func (generator *Generator) request(ctx context.Context, feature string, name string, input any, files ...providers.Part) (providers.Request, prompts.Ref, error) {
+	system, err := generator.Prompts.Render(prompts.System, prompts.SystemInput{
+		Language: Languages[LanguageFromContext(ctx)],
+	})
+	if err != nil {
+		return providers.Request{}, prompts.Ref{}, err
+	}
+
+	var subject string
+	if userId, ok := UserIdFromContext(ctx); ok {
+		subject = userId.Hex()
+	}
+
+	prompt, err := generator.Prompts.RenderFor(name, subject, input)
+	if err != nil {
+		return providers.Request{}, prompts.Ref{}, err
+	}
+
+	parts := append(files, providers.Part{Text: prompt.Text})
+
+	return providers.Request{
+		Feature: feature,
+		System:  system.Text,
+		Parts:   parts,
+	}, prompt.Ref, nil
+}
+
+// Runs the request and decodes the JSON response into output. When the
+// response cannot be decoded or validate reports violations, the model is
+// asked to fix them before giving up with a ValidationError.
+func (generator *Generator) generate(ctx context.Context, request providers.Request, output any, validate func() []string) error {
+	return generator.run(ctx, request, output, validate, nil)
+}
+
+// Same as generate but the response is streamed, onValue receives every top
+// level value and array element as soon as the model finishes writing it.
+// onRetry is called before each repair attempt so partial results already
+// shown can be discarded.
+func (generator *Generator) generateStream(ctx context.Context, request providers.Request, output any, validate func() []string, onValue func(key string, index int, raw json.RawMessage), onRetry func(violations []string)) error {
+	return generator.run(ctx, request, output, validate, &streaming{onValue: onValue, onRetry: onRetry})
+}
+
+type streaming struct {
+	onValue func(key string, index int, raw json.RawMessage)
+	onRetry func(violations []string)
+}
+
+func (generator *Generator) run(ctx context.Context, request providers.Request, output any, validate func() []string, stream *streaming) error {
+	key, ttl := generator.cacheKey(ctx, request)
+	if key != "" {
+		text, ok := generator.Cache.Get(ctx, key)
+		if ok && len(decode(text, output, validate)) == 0 {
+			// Stream clients get the cached values as if the model wrote them
+			if stream != nil {
+				newJSONScanner(stream.onValue).Write(text)
+			}
+			return nil
+		}
+	}
+
+	if generator.Usage != nil {
+		err := generator.Usage.Allow(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	var text string
+	var violations []string
+
+	for attempt := 0; attempt <= generator.MaxRepairs; attempt++ {
+		current := request
+		if attempt > 0 {
+			var err error
+			current, err = generator.repairRequest(request, text, violations)
+			if err != nil {
+				return err
+			}
+			if stream != nil {
+				stream.onRetry(violations)
+			}
+		}
+
+		var response providers.Response
+		var err error
+		if stream != nil {
+			scanner := newJSONScanner(stream.onValue)
+			response, err = providers.Stream(ctx, generator.provider, current, scanner.Write)
+		} else {
+			response, err = generator.provider.Generate(ctx, current)
+		}
+		if err != nil {
+			return err
+		}
+		if generator.Usage != nil {
+			generator.Usage.Record(ctx, request.Feature, response)
+		}
+		text = response.Text
+
+		violations = decode(text, output, validate)
+		if len(violations) == 0 {
+			if key != "" {
+				generator.Cache.Set(ctx, key, request.Feature, text, ttl)
+			}
+			return nil
+		}
+	}
+
+	return &ValidationError{
+		Feature:    request.Feature,
+		Violations: violations,
+		Attempts:   generator.MaxRepairs + 1,
+	}
+}
+
+// Decodes text into output and returns what is wrong with it, if anything.
+func decode(text string, output any, validate func() []string) []string {
+	// Start from an empty value so fields of a previous attempt do not leak
+	value := reflect.ValueOf(output).Elem()
+	value.Set(reflect.Zero(value.Type()))
+
+	err := json.Unmarshal([]byte(text), output)
+	if err != nil {
+		return []string{"the response is not valid JSON: " + err.Error()}
+	}
+
+	if validate == nil {
+		return nil
+	}
+	return validate()
+}
+
+func (generator *Generator) repairRequest(request providers.Request, previous string, violations []string) (providers.Request, error) {
+	prompt, err := generator.Prompts.Render(prompts.Repair, prompts.RepairInput{
+		Violations: violations,
+		Previous:   previous,
+	})
+	if err != nil {
+		return providers.Request{}, err
+	}
+
+	parts := make([]providers.Part, len(request.Parts), len(request.Parts)+1)
+	copy(parts, request.Parts)
+
+	return providers.Request{
+		Feature: request.Feature,
+		System:  request.System,
+		Parts:   append(parts, providers.Part{Text: prompt.Text}),
+	}, nil
+}
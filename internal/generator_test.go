@@ -0,0 +1,362 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+func marshal(t *testing.T, value any) string {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func multipleChoiceExam(questions int) ExamResponse {
+	exam := ExamResponse{Title: "Go concurrency"}
+	for i := range questions {
+		exam.Questions = append(exam.Questions, ExamQuestion{
+			Question:    fmt.Sprintf("Question %v", i+1),
+			Options:     []string{"First", "Second", "Third", "Fourth"},
+			Correct:     int64(i % 4),
+			Explanation: "Because the spec says so.",
+		})
+	}
+
+	return exam
+}
+
+func interview(questions int) InterviewResponse {
+	interview := InterviewResponse{Title: "Backend interview"}
+	for i := range questions {
+		interview.Questions = append(interview.Questions, InterviewQuestion{
+			Question: fmt.Sprintf("Question %v", i+1),
+			Hint:     "Be specific.",
+			Type:     "Technical",
+		})
+	}
+
+	return interview
+}
+
+func modules(count int, final string) ModuleResponse {
+	var response ModuleResponse
+	for i := 1; i < count; i++ {
+		response.Modules = append(response.Modules, Module{Title: fmt.Sprintf("Module %v", i), Objective: "Learn", Order: int64(i), Topic: "Go"})
+	}
+	response.Modules = append(response.Modules, Module{Title: final, Objective: "Apply everything", Order: int64(count), Topic: "Capstone"})
+
+	return response
+}
+
+func steps(count int) StepsResponse {
+	var response StepsResponse
+	for i := 1; i <= count; i++ {
+		response.Steps = append(response.Steps, Step{Title: fmt.Sprintf("Step %v", i), Type: "lesson", Order: int64(i), Difficulty: "easy"})
+	}
+
+	return response
+}
+
+func lesson(sections int) LessonResponse {
+	response := LessonResponse{Title: "Channels", Summary: "How goroutines talk.", KeyTakeaways: []string{"Channels synchronize"}}
+	for i := range sections {
+		response.Sections = append(response.Sections, LessonSection{Heading: fmt.Sprintf("Section %v", i+1), Content: "Content."})
+	}
+
+	return response
+}
+
+func feedback(score int) string {
+	return fmt.Sprintf(`{"feedbacks": [{"feedback": "Clear.", "score": %v, "suggestion": "Add numbers."}], "analysis": "Good.", "strengths": ["Clarity"], "areas_to_improve": ["Depth"]}`, score)
+}
+
+func resumeAnalysis(score int) string {
+	return fmt.Sprintf(`{"title": "Review", "overall_score": %v, "analysis_summary": "Relevant.", "improvement_suggestions": "Add metrics.", "metrics": {"ats_match_score": 70, "clarity_score": 7, "grammar_issues": 1}}`, score)
+}
+
+func questionAnalysis(questionType string) string {
+	return fmt.Sprintf(`{"type": %q, "difficulty": "easy", "explanation": "Opens the interview.", "expected_length": "2", "ideal_answer": {"structure": "Present, past, future", "key_points": ["Current role"], "example": "I am a developer..."}}`, questionType)
+}
+
+// Every generator asks for the prompt once when the model answers with valid
+// output, and asks to fix the listed problems when it does not.
+func TestGenerators(t *testing.T) {
+	resume := []byte("%PDF-1.4")
+	source := ExamResponse{Title: "Go Slices", Questions: []ExamQuestion{
+		{Id: "a", Question: "What does len return?", Options: []string{"Elements", "Capacity", "Bytes", "Index"}, Correct: 2, Explanation: "len counts elements."},
+		{Id: "b", Question: "What does cap return?", Options: []string{"Elements", "Capacity", "Bytes", "Index"}, Correct: 1, Explanation: "cap is the capacity."},
+	}}
+	sourceInterview := interview(5)
+	for i := range sourceInterview.Questions {
+		sourceInterview.Questions[i].Id = fmt.Sprintf("q%v", i)
+	}
+
+	translatedExam := func(questions int) string {
+		exam := translatableExam{Title: "Slices en Go"}
+		for range questions {
+			exam.Questions = append(exam.Questions, translatableExamQuestion{
+				Question:    "¿Qué devuelve?",
+				Options:     []string{"Elementos", "Capacidad", "Bytes", "Índice"},
+				Explanation: "Porque sí.",
+			})
+		}
+		return marshal(t, exam)
+	}
+	translatedInterview := func(title string) string {
+		interview := translatableInterview{Title: title}
+		for range 5 {
+			interview.Questions = append(interview.Questions, translatableInterviewQuestion{Question: "¿Por qué?", Hint: "Sé concreto."})
+		}
+		return marshal(t, interview)
+	}
+
+	tests := []struct {
+		name      string
+		feature   string
+		valid     string
+		invalid   string
+		violation string
+		generate  func(ctx context.Context, generator *Generator) (int, error)
+		want      int
+	}{
+		{
+			"exam", providers.FeatureExam,
+			marshal(t, multipleChoiceExam(10)), marshal(t, multipleChoiceExam(9)),
+			"a easy exam needs exactly 10 questions, got 9",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				exam, err := generator.GenerateExam(ctx, "Go concurrency", "easy", MultipleChoice)
+				return len(exam.Questions), err
+			}, 10,
+		},
+		{
+			"interview", providers.FeatureInterview,
+			marshal(t, interview(5)), marshal(t, interview(4)),
+			"the interview needs exactly 5 questions, got 4",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				interview, err := generator.GenerateInterview(ctx, "Backend developer", "junior", []string{"Go"})
+				return len(interview.Questions), err
+			}, 5,
+		},
+		{
+			"feedback", providers.FeatureFeedback,
+			feedback(8), feedback(0),
+			"feedbacks[0]: score 0 must be between 1 and 10",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				feedback, err := generator.GenerateInterviewFeedback(ctx, []UserInterviewResponse{{Question: "Why us?", Answer: "I like it."}})
+				return len(feedback.Feedbacks), err
+			}, 1,
+		},
+		{
+			"resume", providers.FeatureResume,
+			resumeAnalysis(72), resumeAnalysis(0),
+			"overall_score 0 must be between 1 and 100",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				analysis, err := generator.ResumeAnalyzer(ctx, resume, "Backend developer")
+				return int(analysis.OverallScore), err
+			}, 72,
+		},
+		{
+			"question", providers.FeatureQuestion,
+			questionAnalysis("Behavioral"), questionAnalysis("Trivia"),
+			`type "Trivia" must be one of`,
+			func(ctx context.Context, generator *Generator) (int, error) {
+				analysis, err := generator.GenerateQuestionAnalysis(ctx, "Tell me about yourself")
+				return len(analysis.IdealAnswer.KeyPoints), err
+			}, 1,
+		},
+		{
+			"modules", providers.FeatureModules,
+			marshal(t, modules(11, "Final challenge")), marshal(t, modules(11, " ")),
+			"modules[10]: title is empty",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				modules, err := generator.GenerateModules(ctx, "Backend developer", "junior", "Build APIs.", []string{"Go"})
+				return len(modules.Modules), err
+			}, 11,
+		},
+		{
+			// The final module is found by its order, its title is in the
+			// language of the path
+			"localized modules", providers.FeatureModules,
+			marshal(t, modules(11, "Desafío final")), marshal(t, modules(9, "Desafío final")),
+			"there must be between 10 and 12 modules, got 9",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				modules, err := generator.GenerateModules(WithLanguage(ctx, "es"), "Desarrollador backend", "junior", "Crear APIs.", []string{"Go"})
+				return len(modules.Modules), err
+			}, 11,
+		},
+		{
+			"steps", providers.FeatureSteps,
+			marshal(t, steps(9)), marshal(t, steps(7)),
+			"there must be between 8 and 10 steps, got 7",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				steps, err := generator.GenerateSteps(ctx, "Goroutines", "Run work concurrently", []string{"goroutines"})
+				return len(steps.Steps), err
+			}, 9,
+		},
+		{
+			"lesson", providers.FeatureLesson,
+			marshal(t, lesson(3)), marshal(t, lesson(2)),
+			"there must be between 3 and 5 sections, got 2",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				lesson, err := generator.GenerateLesson(ctx, "Channels", "easy", []string{"select"})
+				return len(lesson.Sections), err
+			}, 3,
+		},
+		{
+			"translated exam", providers.FeatureTranslate,
+			translatedExam(2), translatedExam(1),
+			"the translation needs exactly 2 questions, got 1",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				exam, err := generator.TranslateExam(ctx, source, MultipleChoice, "es")
+				if err == nil && (exam.Questions[0].Id != "a" || exam.Questions[0].Correct != 2) {
+					return 0, fmt.Errorf("answer key not kept: %+v", exam.Questions[0])
+				}
+				return len(exam.Questions), err
+			}, 2,
+		},
+		{
+			"translated interview", providers.FeatureTranslate,
+			translatedInterview("Entrevista"), translatedInterview(" "),
+			"title is empty",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				interview, err := generator.TranslateInterview(ctx, sourceInterview, "es")
+				if err == nil && interview.Questions[4].Id != "q4" {
+					return 0, fmt.Errorf("question ids not kept: %+v", interview.Questions[4])
+				}
+				return len(interview.Questions), err
+			}, 5,
+		},
+		{
+			"short answer grades", providers.FeatureGrade,
+			`{"grades": [{"score": 7, "feedback": "Close."}]}`, `{"grades": [{"score": 11, "feedback": "Close."}]}`,
+			"grades[0]: score 11 must be between 0 and 10",
+			func(ctx context.Context, generator *Generator) (int, error) {
+				grades, err := generator.GradeShortAnswers(ctx, []prompts.ShortAnswer{{Question: "What blocks?", ReferenceAnswer: "Sends on a full channel.", Answer: "Sends."}})
+				return len(grades.Grades), err
+			}, 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := providers.NewFake(test.valid)
+			got, err := test.generate(context.Background(), NewGenerator(fake))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+
+			requests := fake.Requests()
+			if len(requests) != 1 || requests[0].Feature != test.feature {
+				t.Fatalf("made %v requests, want one %v request", len(requests), test.feature)
+			}
+		})
+
+		t.Run(test.name+" repaired", func(t *testing.T) {
+			fake := providers.NewFake(test.invalid, test.valid)
+			got, err := test.generate(context.Background(), NewGenerator(fake))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+
+			requests := fake.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("made %v requests, want 2", len(requests))
+			}
+
+			// The repair request is the original one plus what to fix
+			original, repair := requests[0], requests[1]
+			if len(repair.Parts) != len(original.Parts)+1 || repair.Feature != original.Feature {
+				t.Fatalf("repair request does not extend the original one")
+			}
+			prompt := repair.Parts[len(repair.Parts)-1].Text
+			if !strings.Contains(prompt, test.violation) || !strings.Contains(prompt, test.invalid) {
+				t.Fatalf("repair prompt does not list %q and the previous response:\n%v", test.violation, prompt)
+			}
+		})
+	}
+}
+
+func TestGeneratorRepairsInvalidJSON(t *testing.T) {
+	valid := marshal(t, steps(8))
+	fake := providers.NewFake(valid[:len(valid)/2], valid)
+
+	steps, err := NewGenerator(fake).GenerateSteps(context.Background(), "Goroutines", "Run work concurrently", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps.Steps) != 8 {
+		t.Fatalf("got %v steps, want 8", len(steps.Steps))
+	}
+
+	repair := fake.Requests()[1]
+	if prompt := repair.Parts[len(repair.Parts)-1].Text; !strings.Contains(prompt, "the response is not valid JSON") {
+		t.Fatalf("repair prompt does not report the invalid JSON:\n%v", prompt)
+	}
+}
+
+func TestGeneratorGivesUp(t *testing.T) {
+	invalid := marshal(t, lesson(1))
+	fake := providers.NewFake(invalid, invalid, invalid, marshal(t, lesson(3)))
+
+	generator := NewGenerator(fake)
+	_, err := generator.GenerateLesson(context.Background(), "Channels", "easy", nil)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a ValidationError", err)
+	}
+	if validationErr.Feature != providers.FeatureLesson || validationErr.Attempts != generator.MaxRepairs+1 {
+		t.Fatalf("got %+v", validationErr)
+	}
+	if len(fake.Requests()) != generator.MaxRepairs+1 {
+		t.Fatalf("made %v requests, want %v", len(fake.Requests()), generator.MaxRepairs+1)
+	}
+}
+
+// Ids written by the model are replaced, they may repeat or not be usable as
+// field names.
+func TestGenerateExamAssignsIds(t *testing.T) {
+	exam := multipleChoiceExam(10)
+	for i := range exam.Questions {
+		exam.Questions[i].Id = "q.1"
+	}
+
+	generator := NewGenerator(providers.NewFake(marshal(t, exam)))
+
+	result, err := generator.GenerateExam(context.Background(), "Go concurrency", "easy", MultipleChoice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for i, question := range result.Questions {
+		if question.Id == "q.1" || question.Id == "" || seen[question.Id] {
+			t.Fatalf("questions[%v] has id %q", i, question.Id)
+		}
+		seen[question.Id] = true
+
+		// Shuffling keeps the answer key pointing at the same option
+		original := exam.Questions[i]
+		if question.Options[question.Correct] != original.Options[original.Correct] {
+			t.Fatalf("questions[%v]: correct option moved from %q to %q", i, original.Options[original.Correct], question.Options[question.Correct])
+		}
+	}
+}
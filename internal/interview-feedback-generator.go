@@ -1,16 +1,19 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 
-	"google.golang.org/genai"
-	"prepai.app/configs"
+	"prepai.app/prompts"
+	"prepai.app/providers"
 )
 
+// QuestionId is optional, when it is set the question is the one of the
+// interview with that id.
 type UserInterviewResponse struct {
-	Question string
-	Answer   string
+	QuestionId string `json:"question_id"`
+	Question   string `json:"question"`
+	Answer     string `json:"answer"`
 }
 
 type InterviewFeedback struct {
@@ -24,58 +27,68 @@ type InterviewFeedbackResponse struct {
 	Analysis       string              `json:"analysis"`
 	Strengths      []string            `json:"strengths"`
 	AreasToImprove []string            `json:"areas_to_improve"`
+	Prompt         prompts.Ref         `json:"-"`
 }
 
-func GenerateInterviewFeedback(responses []UserInterviewResponse) (InterviewFeedbackResponse, error) {
-	prompt := fmt.Sprintf(`
-		Generate feedback on how the interviewee answered the following questions.
-
-		This is the JSON containing the questions and answers: %v
-
-		For each question, provide:
-		- Feedback on how well the interviewee answered the question, considering vocabulary, technical terminology, structure, depth of knowledge, and relevance to the question.
-		- The feedback must be between 3 to 5 sentences.
-		- If the response is empty or missing, state clearly: "This question was not answered."
-		- Provide a score from 1 to 10 (1 = very poor, 10 = excellent) based on the quality of the response.
-		- Suggestion must give a direct and practical advice for how to improve the answer.
-
-		Then, generate an overall interview analysis, taking into account:
-		- Use of vocabulary and domain-specific terminology.
-		- Clarity and confidence in communication.
-		- Word repetition or redundancy.
-		- Excessive use of filler words (e.g., "um", "like", "you know").
-		- Overall ability to communicate thoughts effectively and professionally.
-		- The overall analysis must be between 5 to 8 sentences.
-
-		In addition provide:
-		- Strengths and areas to improve.
-
-		Format the output in the following JSON schema:
-		{
-		"feedbacks": [
-			{
-			"feedback": string,
-			"score": int,
-			"suggestion": string
-			}
-		],
-		"analysis": string,
-		"strengths": [string],
-  		"areas_to_improve": [string]
-		}
-	`, responses)
+func (generator *Generator) feedbackRequest(ctx context.Context, responses []UserInterviewResponse) (providers.Request, prompts.Ref, error) {
+	answers := make([]prompts.Answer, len(responses))
+	for i, response := range responses {
+		answers[i] = prompts.Answer{Question: response.Question, Answer: response.Answer}
+	}
+
+	return generator.request(ctx, providers.FeatureFeedback, prompts.Feedback, prompts.FeedbackInput{
+		Responses: answers,
+	})
+}
+
+func (generator *Generator) GenerateInterviewFeedback(ctx context.Context, responses []UserInterviewResponse) (InterviewFeedbackResponse, error) {
+	request, ref, err := generator.feedbackRequest(ctx, responses)
+	if err != nil {
+		return InterviewFeedbackResponse{}, err
+	}
+
+	var feedback InterviewFeedbackResponse
 
-	result, err := configs.Gemini(genai.Text(prompt))
+	err = generator.generate(ctx, request, &feedback, func() []string {
+		return feedback.Validate(len(responses))
+	})
+	if err != nil {
+		return InterviewFeedbackResponse{}, err
+	}
+
+	feedback.Prompt = ref
+	return feedback, nil
+}
+
+// Streams the feedback of each answer as it completes, followed by the
+// overall analysis fields.
+func (generator *Generator) StreamInterviewFeedback(ctx context.Context, responses []UserInterviewResponse, emit func(StreamEvent)) (InterviewFeedbackResponse, error) {
+	request, ref, err := generator.feedbackRequest(ctx, responses)
 	if err != nil {
 		return InterviewFeedbackResponse{}, err
 	}
 
 	var feedback InterviewFeedbackResponse
 
-	err = json.Unmarshal([]byte(result), &feedback)
+	onValue := func(key string, index int, raw json.RawMessage) {
+		switch {
+		case key == "feedbacks" && index >= 0:
+			var item InterviewFeedback
+			if json.Unmarshal(raw, &item) == nil {
+				emit(StreamEvent{Type: StreamFeedback, Index: index, Data: item})
+			}
+		case key != "feedbacks" && index < 0:
+			emit(StreamEvent{Type: StreamField, Key: key, Index: index, Data: raw})
+		}
+	}
+
+	err = generator.generateStream(ctx, request, &feedback, func() []string {
+		return feedback.Validate(len(responses))
+	}, onValue, retryEvent(emit))
 	if err != nil {
 		return InterviewFeedbackResponse{}, err
 	}
 
+	feedback.Prompt = ref
 	return feedback, nil
 }
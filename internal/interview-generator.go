@@ -1,14 +1,14 @@
 package internal
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 
-	"google.golang.org/genai"
-	"prepai.app/configs"
+	"prepai.app/prompts"
+	"prepai.app/providers"
 )
 
 type InterviewQuestion struct {
+	Id       string `json:"id"`
 	Question string `json:"question"`
 	Hint     string `json:"hint"`
 	Type     string `json:"type"`
@@ -17,41 +17,29 @@ type InterviewQuestion struct {
 type InterviewResponse struct {
 	Title     string              `json:"title"`
 	Questions []InterviewQuestion `json:"questions"`
+	Prompt    prompts.Ref         `json:"-"`
 }
 
-func GenerateInterview(jobRole string, jobLevel string, topics []string) (InterviewResponse, error) {
-	prompt := fmt.Sprintf(`
-		Generate 5 job interview questions for a role of %v with a %v. And a title for the interview.
-		The interview topics are: %v.
-		For each question provide:
-		- The question.
-		- A hint (Short text to help the interviewee).
-		- Question type ("Behavioral", "Technical", "HR", etc)
-
-		Follow this JSON schema:
-		{
-			"title": string,
-			"questions": [
-				{
-					"question": string,
-					"hint": string,
-					"type": string
-				}
-			]
-		}
-	`, jobRole, jobLevel, topics)
-
-	result, err := configs.Gemini(genai.Text(prompt))
+func (generator *Generator) GenerateInterview(ctx context.Context, jobRole string, jobLevel string, topics []string) (InterviewResponse, error) {
+	request, ref, err := generator.request(ctx, providers.FeatureInterview, prompts.Interview, prompts.InterviewInput{
+		JobRole:  jobRole,
+		JobLevel: jobLevel,
+		Topics:   topics,
+	})
 	if err != nil {
 		return InterviewResponse{}, err
 	}
 
 	var questions InterviewResponse
 
-	err = json.Unmarshal([]byte(result), &questions)
+	err = generator.generate(ctx, request, &questions, func() []string {
+		return questions.Validate()
+	})
 	if err != nil {
 		return InterviewResponse{}, err
 	}
 
+	questions.assignIds()
+	questions.Prompt = ref
 	return questions, nil
 }
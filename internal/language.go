@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+const DefaultLanguage = "en"
+
+// Languages content can be generated in, by ISO 639-1 code.
+var Languages = map[string]string{
+	"en": "English",
+	"es": "Spanish",
+	"pt": "Portuguese",
+	"fr": "French",
+	"de": "German",
+	"it": "Italian",
+}
+
+// Returns the code in its canonical form and whether it is supported.
+func ParseLanguage(code string) (string, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	_, ok := Languages[code]
+	return code, ok
+}
+
+func LanguageCodes() []string {
+	codes := make([]string, 0, len(Languages))
+	for code := range Languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+type languageKey struct{}
+
+// Generations made with this context are written in the language.
+func WithLanguage(ctx context.Context, code string) context.Context {
+	return context.WithValue(ctx, languageKey{}, code)
+}
+
+// Falls back to the default language when the context has none or an
+// unsupported one.
+func LanguageFromContext(ctx context.Context) string {
+	code, _ := ctx.Value(languageKey{}).(string)
+	if _, ok := Languages[code]; !ok {
+		return DefaultLanguage
+	}
+
+	return code
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"context"
+
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+type LessonSection struct {
+	Heading string `json:"heading"`
+	Content string `json:"content"`
+	Example string `json:"example"`
+}
+
+type LessonResponse struct {
+	Title        string          `json:"title"`
+	Summary      string          `json:"summary"`
+	Sections     []LessonSection `json:"sections"`
+	KeyTakeaways []string        `json:"key_takeaways"`
+	Prompt       prompts.Ref     `json:"-"`
+}
+
+func (generator *Generator) GenerateLesson(ctx context.Context, title string, difficulty string, topics []string) (LessonResponse, error) {
+	request, ref, err := generator.request(ctx, providers.FeatureLesson, prompts.Lesson, prompts.LessonInput{
+		Title:      title,
+		Difficulty: difficulty,
+		Topics:     topics,
+	})
+	if err != nil {
+		return LessonResponse{}, err
+	}
+
+	var lesson LessonResponse
+
+	err = generator.generate(ctx, request, &lesson, func() []string {
+		return lesson.Validate()
+	})
+	if err != nil {
+		return LessonResponse{}, err
+	}
+
+	lesson.Prompt = ref
+	return lesson, nil
+}
@@ -1,12 +1,10 @@
 package internal
 
 import (
-	"encoding/json"
-	"fmt"
-	"strings"
+	"context"
 
-	"google.golang.org/genai"
-	"prepai.app/configs"
+	"prepai.app/prompts"
+	"prepai.app/providers"
 )
 
 type Module struct {
@@ -17,54 +15,34 @@ type Module struct {
 }
 
 type ModuleResponse struct {
-	Modules []Module `json:"modules"`
+	Modules []Module    `json:"modules"`
+	Prompt  prompts.Ref `json:"-"`
 }
 
-func GenerateModules(jobRole string, jobLevel string, jobDescription string, topics []string) (ModuleResponse, error) {
-	topicsStr := strings.Join(topics, ", ")
+func (generator *Generator) GenerateModules(ctx context.Context, jobRole string, jobLevel string, jobDescription string, topics []string) (ModuleResponse, error) {
 	if len(jobDescription) > 500 {
 		jobDescription = jobDescription[:497] + "..."
 	}
 
-	prompt := fmt.Sprintf(`
-		Create structured, gamified modules for the following role: %v at a %v-level. The job description is: "%v".
-		The modules must help the candidate prepare for a job interview for this role.
-
-			- Include between 10 and 12 modules in total.
-			- Each module should have:
-				- Title (Descriptive of the module).
-				- Objective (What is the aim of that module).
-				- Topic (Topics included in the module separated by ",").
-				- Order (To sort the modules)
-			- Some modules should focus on technical skills, others on soft skills.
-			- The last module needs to be: "Final challenge".
-			- Sort them from easier to harder in terms of difficulty (1: easiest and X: hardesr). This number is the "order" field.
-			- In addition to the role, level and description, this are topics that the interviewee needs to know: %v
-
-		Follow this JSON schema:
-		{
-			"modules": [
-				{
-					"title": string,
-					"objective": string,
-					"topic": string,
-					"order": int64
-				}
-			]
-		}
-	`, jobRole, jobLevel, jobDescription, topicsStr)
-
-	result, err := configs.Gemini(genai.Text(prompt))
+	request, ref, err := generator.request(ctx, providers.FeatureModules, prompts.Modules, prompts.ModulesInput{
+		JobRole:        jobRole,
+		JobLevel:       jobLevel,
+		JobDescription: jobDescription,
+		Topics:         topics,
+	})
 	if err != nil {
 		return ModuleResponse{}, err
 	}
 
 	var modules ModuleResponse
 
-	err = json.Unmarshal([]byte(result), &modules)
+	err = generator.generate(ctx, request, &modules, func() []string {
+		return modules.Validate()
+	})
 	if err != nil {
 		return ModuleResponse{}, err
 	}
 
+	modules.Prompt = ref
 	return modules, nil
 }
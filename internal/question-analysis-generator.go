@@ -1,11 +1,10 @@
 package internal
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 
-	"google.golang.org/genai"
-	"prepai.app/configs"
+	"prepai.app/prompts"
+	"prepai.app/providers"
 )
 
 type QuestionAnswer struct {
@@ -20,47 +19,26 @@ type QuestionAnalysisResponse struct {
 	Explanation    string         `json:"explanation"`
 	ExpectedLength string         `json:"expected_length"`
 	IdealAnswer    QuestionAnswer `json:"ideal_answer"`
+	Prompt         prompts.Ref    `json:"-"`
 }
 
-func GenerateQuestionAnalysis(question string) (QuestionAnalysisResponse, error) {
-	prompt := fmt.Sprintf(`
-		Analyze the following interview question: %v.
-
-		Return a JSON object with the following fields:
-		- "type": The type of the question. Choose one of: "Behavioral", "Technical", "HR", "Situational", or "Other".
-		- "difficulty": One of: "easy", "medium", or "hard".
-		- "explanation": A 2-3 sentence explanation of what the question evaluates and why interviewers ask it.
-		- "expected_length": How long in minutes should the interviewee take to answer.
-		- "ideal_answer": An object that contains:
-			- "structure": Describe the best format or method to answer the question (e.g., STAR, technical breakdown, etc.).
-			- "key_points": A list of the most important concepts, points, or themes the answer should include.
-			- "example": A sample ideal answer (5-8 lines) that would score highly in a real interview.
-
-		Respond only in the following JSON format:
-		{
-			"type": string,
-			"difficulty": string,
-			"explanation": string,
-			"expected_length": string,
-			"ideal_answer": {
-				"structure": string,
-				"key_points": [string],
-				"example": string
-			}
-		}
-	`, question)
-
-	result, err := configs.Gemini(genai.Text(prompt))
+func (generator *Generator) GenerateQuestionAnalysis(ctx context.Context, question string) (QuestionAnalysisResponse, error) {
+	request, ref, err := generator.request(ctx, providers.FeatureQuestion, prompts.Question, prompts.QuestionInput{
+		Question: question,
+	})
 	if err != nil {
 		return QuestionAnalysisResponse{}, err
 	}
 
 	var questionAnalysis QuestionAnalysisResponse
 
-	err = json.Unmarshal([]byte(result), &questionAnalysis)
+	err = generator.generate(ctx, request, &questionAnalysis, func() []string {
+		return questionAnalysis.Validate()
+	})
 	if err != nil {
 		return QuestionAnalysisResponse{}, err
 	}
 
+	questionAnalysis.Prompt = ref
 	return questionAnalysis, nil
 }
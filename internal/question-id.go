@@ -0,0 +1,24 @@
+package internal
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// Questions keep their id for as long as the exam or interview exists,
+// answers refer to questions by it instead of by position. Regenerating
+// the content gives every question a new one.
+func NewQuestionId() string {
+	return bson.NewObjectID().Hex()
+}
+
+// Generated questions always get new ids, any the model wrote may repeat
+// across chunks or not be usable as a field name.
+func (exam *ExamResponse) assignIds() {
+	for i := range exam.Questions {
+		exam.Questions[i].Id = NewQuestionId()
+	}
+}
+
+func (interview *InterviewResponse) assignIds() {
+	for i := range interview.Questions {
+		interview.Questions[i].Id = NewQuestionId()
+	}
+}
@@ -1,14 +1,11 @@
 package internal
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io"
-	"mime/multipart"
 
-	"google.golang.org/genai"
-	"prepai.app/configs"
+	"prepai.app/prompts"
+	"prepai.app/providers"
 )
 
 type Metrics struct {
@@ -21,72 +18,62 @@ type Metrics struct {
 }
 
 type ResumeAnalyzerResponse struct {
-	Title                  string  `json:"title"`
-	OverallScore           int64   `json:"overall_score"`
-	AnalysisSummary        string  `json:"analysis_summary"`
-	ImprovementSuggestions string  `json:"improvement_suggestions"`
-	Metrics                Metrics `json:"metrics"`
+	Title                  string      `json:"title"`
+	OverallScore           int64       `json:"overall_score"`
+	AnalysisSummary        string      `json:"analysis_summary"`
+	ImprovementSuggestions string      `json:"improvement_suggestions"`
+	Metrics                Metrics     `json:"metrics"`
+	Prompt                 prompts.Ref `json:"-"`
 }
 
-func ResumeAnalyzer(file multipart.File, header *multipart.FileHeader, jobDescription string) (ResumeAnalyzerResponse, error) {
-	bs := make([]byte, header.Size)
+func (generator *Generator) resumeRequest(ctx context.Context, resume []byte, jobDescription string) (providers.Request, prompts.Ref, error) {
+	return generator.request(ctx, providers.FeatureResume, prompts.Resume, prompts.ResumeInput{
+		JobDescription: jobDescription,
+	}, providers.Part{MIMEType: "application/pdf", Data: resume})
+}
 
-	_, err := bufio.NewReader(file).Read(bs)
-	if err != nil && err != io.EOF {
+func (generator *Generator) ResumeAnalyzer(ctx context.Context, resume []byte, jobDescription string) (ResumeAnalyzerResponse, error) {
+	request, ref, err := generator.resumeRequest(ctx, resume, jobDescription)
+	if err != nil {
 		return ResumeAnalyzerResponse{}, err
 	}
 
-	prompt := fmt.Sprintf(`
-		You are an expert technical recruiter and resume reviewer. Analyze the following resume in relation to the provided job description.
-		Evaluate and return your analysis using the JSON format described below. Be objective, precise, and explain each metric when necessary.
-		You need to talk/address as if you were talking to the candidate.
-
-		Job description:
-		"%v"
-
-		Return the results using this JSON schema:
-		{
-			"title": string, // A title for this analysis (no more than one line)
-			"overall_score": int, // From 1 to 100: how well the resume fits the job,
-			"analysis_summary": string, // A brief 5-8 line summary of the resume quality and fit,
-			"metrics": {
-				"ats_match_score": int, // (1-100) How well the resume matches keywords/structure from the job,
-				"clarity_score": int, // (1-10) Based on grammar, conciseness, and readability,
-				"grammar_issues": int, // Total number of grammar or spelling problems,
-				"soft_vs_hard_skill_balance": string, // e.g., "Balanced", "Too much soft", "Too technical",
-				"resume_length_feedback": string, // e.g., "Appropriate", "Too long for a junior", "Too short",
-				"filler_word_usage": string, // e.g., "Minimal", "Moderate", "Heavy use of vague language",
-			},
-			"improvement_suggestions":
-				string // Improvements to enhance the resume
-		}
+	var analysis ResumeAnalyzerResponse
 
-	`, jobDescription)
-	parts := []*genai.Part{
-		{
-			InlineData: &genai.Blob{
-				MIMEType: "application/pdf",
-				Data:     bs,
-			},
-		},
-		genai.NewPartFromText(prompt),
-	}
-	contents := []*genai.Content{
-		genai.NewContentFromParts(parts, genai.RoleUser),
+	err = generator.generate(ctx, request, &analysis, func() []string {
+		return analysis.Validate()
+	})
+	if err != nil {
+		return ResumeAnalyzerResponse{}, err
 	}
 
-	result, err := configs.Gemini(contents)
+	analysis.Prompt = ref
+	return analysis, nil
+}
+
+// Streams every field of the analysis (score, summary, metrics...) as it
+// completes.
+func (generator *Generator) StreamResumeAnalysis(ctx context.Context, resume []byte, jobDescription string, emit func(StreamEvent)) (ResumeAnalyzerResponse, error) {
+	request, ref, err := generator.resumeRequest(ctx, resume, jobDescription)
 	if err != nil {
 		return ResumeAnalyzerResponse{}, err
 	}
 
 	var analysis ResumeAnalyzerResponse
 
-	err = json.Unmarshal([]byte(result), &analysis)
+	onValue := func(key string, index int, raw json.RawMessage) {
+		if index < 0 {
+			emit(StreamEvent{Type: StreamField, Key: key, Index: index, Data: raw})
+		}
+	}
+
+	err = generator.generateStream(ctx, request, &analysis, func() []string {
+		return analysis.Validate()
+	}, onValue, retryEvent(emit))
 	if err != nil {
-		fmt.Print(err)
 		return ResumeAnalyzerResponse{}, err
 	}
 
+	analysis.Prompt = ref
 	return analysis, nil
 }
@@ -1,12 +1,10 @@
 package internal
 
 import (
-	"encoding/json"
-	"fmt"
-	"strings"
+	"context"
 
-	"google.golang.org/genai"
-	"prepai.app/configs"
+	"prepai.app/prompts"
+	"prepai.app/providers"
 )
 
 type Step struct {
@@ -17,48 +15,29 @@ type Step struct {
 }
 
 type StepsResponse struct {
-	Steps []Step `json:"steps"`
+	Steps  []Step      `json:"steps"`
+	Prompt prompts.Ref `json:"-"`
 }
 
-func GenerateSteps(moduleTitle string, moduleDescription string, topics []string) (StepsResponse, error) {
-	topicsStr := strings.Join(topics, ", ")
-
-	prompt := fmt.Sprintf(`
-		Create structured, gamified steps for the following module: %v. The module description is: "%v", with this topics: %v.
-		The steps must help the candidate understand and practice for this module.
-
-			- Include between 8 and 10 steps in total.
-			- Each module should have:
-				- Title (Descriptive of the step).
-				- Type (What type of activity is: mock exam, open question, mock interview, lesson, etc).
-				- Order (To sort the steps)
-			- The last module needs to be an exam or interview to sum up this module.
-			- Sort them from easier to harder in terms of difficulty (1: easiest and X: hardesr). This number is the "order" field and add the appropiate difficulty.
-
-		Follow this JSON schema:
-		{
-			"steps": [
-				{
-					"title": string,
-					"type": string,
-					"order": int64,
-					"difficulty": string
-				}
-			]
-		}
-	`, moduleTitle, moduleDescription, topicsStr)
-
-	result, err := configs.Gemini(genai.Text(prompt))
+func (generator *Generator) GenerateSteps(ctx context.Context, moduleTitle string, moduleDescription string, topics []string) (StepsResponse, error) {
+	request, ref, err := generator.request(ctx, providers.FeatureSteps, prompts.Steps, prompts.StepsInput{
+		ModuleTitle:       moduleTitle,
+		ModuleDescription: moduleDescription,
+		Topics:            topics,
+	})
 	if err != nil {
 		return StepsResponse{}, err
 	}
 
 	var steps StepsResponse
 
-	err = json.Unmarshal([]byte(result), &steps)
+	err = generator.generate(ctx, request, &steps, func() []string {
+		return steps.Validate()
+	})
 	if err != nil {
 		return StepsResponse{}, err
 	}
 
+	steps.Prompt = ref
 	return steps, nil
 }
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"encoding/json"
+)
+
+const (
+	StreamField    = "field"
+	StreamQuestion = "question"
+	StreamFeedback = "feedback"
+	StreamRetry    = "retry"
+)
+
+// StreamEvent is a partial result pushed while the model is still writing.
+// Type is used as the server-sent event name.
+type StreamEvent struct {
+	Type  string `json:"-"`
+	Key   string `json:"key,omitempty"`
+	Index int    `json:"index"`
+	Data  any    `json:"data"`
+}
+
+// Tells the client to drop what it rendered so far, the model is fixing its
+// response and everything will be sent again.
+func retryEvent(emit func(StreamEvent)) func(violations []string) {
+	return func(violations []string) {
+		emit(StreamEvent{Type: StreamRetry, Index: -1, Data: violations})
+	}
+}
+
+// jsonScanner reads a JSON object as it arrives and reports every top level
+// value once it is complete, with index -1, and every element of a top level
+// array, with its position. Anything outside the root object is ignored.
+type jsonScanner struct {
+	onValue func(key string, index int, raw json.RawMessage)
+
+	buffer   []byte
+	stack    []byte
+	inString bool
+	escaped  bool
+	literal  bool
+
+	key         string
+	keyStart    int
+	expectValue bool
+	fieldStart  int
+	itemStart   int
+	index       int
+}
+
+func newJSONScanner(onValue func(key string, index int, raw json.RawMessage)) *jsonScanner {
+	return &jsonScanner{
+		onValue:    onValue,
+		keyStart:   -1,
+		fieldStart: -1,
+		itemStart:  -1,
+	}
+}
+
+func (scanner *jsonScanner) Write(chunk string) {
+	start := len(scanner.buffer)
+	scanner.buffer = append(scanner.buffer, chunk...)
+
+	for i := start; i < len(scanner.buffer); i++ {
+		c := scanner.buffer[i]
+
+		if scanner.inString {
+			switch {
+			case scanner.escaped:
+				scanner.escaped = false
+			case c == '\\':
+				scanner.escaped = true
+			case c == '"':
+				scanner.inString = false
+				scanner.end(i + 1)
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			scanner.endLiteral(i)
+			scanner.begin(i, c)
+			scanner.inString = true
+		case '{', '[':
+			scanner.endLiteral(i)
+			scanner.begin(i, c)
+			scanner.stack = append(scanner.stack, c)
+			if c == '[' && len(scanner.stack) == 2 {
+				scanner.index = 0
+			}
+		case '}', ']':
+			scanner.endLiteral(i)
+			if len(scanner.stack) > 0 {
+				scanner.stack = scanner.stack[:len(scanner.stack)-1]
+				scanner.end(i + 1)
+			}
+		case ':':
+			scanner.endLiteral(i)
+			if len(scanner.stack) == 1 {
+				scanner.expectValue = true
+			}
+		case ',', ' ', '\t', '\r', '\n':
+			scanner.endLiteral(i)
+		default:
+			if !scanner.literal {
+				scanner.begin(i, c)
+				scanner.literal = true
+			}
+		}
+	}
+}
+
+// Marks where a value starts when it sits at one of the depths we report.
+func (scanner *jsonScanner) begin(i int, c byte) {
+	switch {
+	case len(scanner.stack) == 1 && scanner.expectValue:
+		scanner.fieldStart = i
+		scanner.expectValue = false
+	case len(scanner.stack) == 1 && c == '"':
+		scanner.keyStart = i
+	case len(scanner.stack) == 2 && scanner.stack[1] == '[':
+		scanner.itemStart = i
+	}
+}
+
+// Called when a value ending right before end finished at the current depth.
+func (scanner *jsonScanner) end(end int) {
+	switch {
+	case len(scanner.stack) == 1 && scanner.fieldStart >= 0:
+		scanner.onValue(scanner.key, -1, json.RawMessage(scanner.buffer[scanner.fieldStart:end]))
+		scanner.fieldStart = -1
+	case len(scanner.stack) == 1 && scanner.keyStart >= 0:
+		var key string
+		if json.Unmarshal(scanner.buffer[scanner.keyStart:end], &key) == nil {
+			scanner.key = key
+		}
+		scanner.keyStart = -1
+	case len(scanner.stack) == 2 && scanner.stack[1] == '[' && scanner.itemStart >= 0:
+		scanner.onValue(scanner.key, scanner.index, json.RawMessage(scanner.buffer[scanner.itemStart:end]))
+		scanner.itemStart = -1
+		scanner.index++
+	}
+}
+
+func (scanner *jsonScanner) endLiteral(i int) {
+	if scanner.literal {
+		scanner.literal = false
+		scanner.end(i)
+	}
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type scannedValue struct {
+	Key   string
+	Index int
+	Raw   string
+}
+
+// What the scanner should report for document, worked out by parsing it in
+// one go: every element of a top level array, then the value itself.
+func parsedValues(t *testing.T, document string) []scannedValue {
+	t.Helper()
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(document)))
+	_, err := decoder.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values []scannedValue
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := token.(string)
+
+		var raw json.RawMessage
+		err = decoder.Decode(&raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if raw[0] == '[' {
+			var items []json.RawMessage
+			err = json.Unmarshal(raw, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, item := range items {
+				values = append(values, scannedValue{Key: key, Index: i, Raw: string(item)})
+			}
+		}
+		values = append(values, scannedValue{Key: key, Index: -1, Raw: string(raw)})
+	}
+
+	return values
+}
+
+func scan(chunks ...string) []scannedValue {
+	var values []scannedValue
+	scanner := newJSONScanner(func(key string, index int, raw json.RawMessage) {
+		values = append(values, scannedValue{Key: key, Index: index, Raw: string(raw)})
+	})
+	for _, chunk := range chunks {
+		scanner.Write(chunk)
+	}
+
+	return values
+}
+
+func TestJSONScanner(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+	}{
+		{"empty", `{}`},
+		{"strings", `{"title": "Go basics", "subject": "Go"}`},
+		{"escaped quotes", `{"question": "What does \"defer\" do?", "options": ["Runs \"later\"", "Nothing"]}`},
+		{"backslashes", `{"path": "C:\\Users\\", "regex": ["\\d+\\\"", "\\\\"]}`},
+		{"escaped key", `{"a\"b": 1, "c\\": [2]}`},
+		{"unicode", `{"title": "Programación \u00e9", "items": ["ñ", "\u0041"]}`},
+		{"numbers", `{"score": 87, "ratio": -0.5, "big": 1e10, "scores": [1, 2.5, -3, 4E-2]}`},
+		{"literals", `{"passed": true, "pinned": false, "prompt": null, "flags": [true,false,null]}`},
+		{"nested objects", `{"exam": {"title": "Go", "meta": {"level": "easy"}}, "questions": [{"question": "Q1", "options": ["a", "b"], "correct": 0}, {"question": "Q2", "options": [], "correct": 1}]}`},
+		{"nested arrays", `{"matrix": [[1, 2], [3, [4, 5]], []], "empty": []}`},
+		{"brackets in strings", `{"text": "}{][,:", "items": ["]", "[", "{\"a\": 1}"]}`},
+		{"whitespace", "{\n\t\"title\" : \"Go\" ,\r\n\t\"items\" : [ 1 ,\n 2 ] ,\n\t\"done\" : true\n}"},
+		{"trailing literal", `{"a": [1], "b": 12}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := parsedValues(t, test.document)
+
+			got := scan(test.document)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("in one write got %+v, want %+v", got, want)
+			}
+
+			for split := 1; split < len(test.document); split++ {
+				got := scan(test.document[:split], test.document[split:])
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("split at %v (%q) got %+v, want %+v", split, test.document[:split], got, want)
+				}
+			}
+
+			chunks := make([]string, len(test.document))
+			for i := 0; i < len(test.document); i++ {
+				chunks[i] = test.document[i : i+1]
+			}
+			got = scan(chunks...)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("byte by byte got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// Models sometimes wrap the object in a code fence, only the object counts.
+func TestJSONScannerIgnoresOutsideRoot(t *testing.T) {
+	document := `{"title": "Go", "items": [1, 2]}`
+	want := parsedValues(t, document)
+
+	got := scan("```json\n", document, "\n```")
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+// What the model gets to translate. Correct answers and question types are
+// left out, they are copied from the original.
+type translatableExamQuestion struct {
+	Question        string   `json:"question"`
+	Options         []string `json:"options,omitempty"`
+	ReferenceAnswer string   `json:"reference_answer,omitempty"`
+	Explanation     string   `json:"explanation"`
+}
+
+type translatableExam struct {
+	Title     string                     `json:"title"`
+	Questions []translatableExamQuestion `json:"questions"`
+}
+
+type translatableInterviewQuestion struct {
+	Question string `json:"question"`
+	Hint     string `json:"hint"`
+}
+
+type translatableInterview struct {
+	Title     string                          `json:"title"`
+	Questions []translatableInterviewQuestion `json:"questions"`
+}
+
+// Translates the exam text into the language. Questions and options keep
+// their order so the correct answers stay at the same indices, true-false
+// options are kept as they are.
+func (generator *Generator) TranslateExam(ctx context.Context, exam ExamResponse, examType string, language string) (ExamResponse, error) {
+	source := translatableExam{Title: exam.Title}
+	for _, question := range exam.Questions {
+		source.Questions = append(source.Questions, translatableExamQuestion{
+			Question:        question.Question,
+			Options:         question.Options,
+			ReferenceAnswer: question.ReferenceAnswer,
+			Explanation:     question.Explanation,
+		})
+	}
+
+	text, err := json.MarshalIndent(source, "", "\t")
+	if err != nil {
+		return ExamResponse{}, err
+	}
+
+	ctx = WithLanguage(ctx, language)
+	request, ref, err := generator.request(ctx, providers.FeatureTranslate, prompts.TranslateExam, prompts.TranslateExamInput{
+		Language: Languages[LanguageFromContext(ctx)],
+		Type:     examType,
+		Exam:     string(text),
+	})
+	if err != nil {
+		return ExamResponse{}, err
+	}
+
+	var translated translatableExam
+
+	err = generator.generate(ctx, request, &translated, func() []string {
+		return translated.Validate(source, examType)
+	})
+	if err != nil {
+		return ExamResponse{}, err
+	}
+
+	// Questions keep their ids, they are the same questions in another language
+	result := ExamResponse{
+		Title:     translated.Title,
+		Questions: make([]ExamQuestion, len(exam.Questions)),
+		Prompt:    ref,
+		Seed:      exam.Seed,
+	}
+	for i, question := range exam.Questions {
+		options := translated.Questions[i].Options
+		if examType == TrueFalse {
+			options = question.Options
+		}
+
+		result.Questions[i] = ExamQuestion{
+			Id:              question.Id,
+			Type:            question.Type,
+			Question:        translated.Questions[i].Question,
+			Options:         options,
+			Correct:         question.Correct,
+			CorrectOptions:  question.CorrectOptions,
+			CorrectOrder:    question.CorrectOrder,
+			ReferenceAnswer: translated.Questions[i].ReferenceAnswer,
+			Explanation:     translated.Questions[i].Explanation,
+		}
+	}
+
+	return result, nil
+}
+
+// Translates the interview text into the language, question types are kept
+// as they are.
+func (generator *Generator) TranslateInterview(ctx context.Context, interview InterviewResponse, language string) (InterviewResponse, error) {
+	source := translatableInterview{Title: interview.Title}
+	for _, question := range interview.Questions {
+		source.Questions = append(source.Questions, translatableInterviewQuestion{
+			Question: question.Question,
+			Hint:     question.Hint,
+		})
+	}
+
+	text, err := json.MarshalIndent(source, "", "\t")
+	if err != nil {
+		return InterviewResponse{}, err
+	}
+
+	ctx = WithLanguage(ctx, language)
+	request, ref, err := generator.request(ctx, providers.FeatureTranslate, prompts.TranslateInterview, prompts.TranslateInterviewInput{
+		Language:  Languages[LanguageFromContext(ctx)],
+		Interview: string(text),
+	})
+	if err != nil {
+		return InterviewResponse{}, err
+	}
+
+	var translated translatableInterview
+
+	err = generator.generate(ctx, request, &translated, func() []string {
+		return translated.Validate(source)
+	})
+	if err != nil {
+		return InterviewResponse{}, err
+	}
+
+	result := InterviewResponse{
+		Title:     translated.Title,
+		Questions: make([]InterviewQuestion, len(interview.Questions)),
+		Prompt:    ref,
+	}
+	for i, question := range interview.Questions {
+		result.Questions[i] = InterviewQuestion{
+			Id:       question.Id,
+			Question: translated.Questions[i].Question,
+			Hint:     translated.Questions[i].Hint,
+			Type:     question.Type,
+		}
+	}
+
+	return result, nil
+}
+
+func (exam translatableExam) Validate(source translatableExam, examType string) []string {
+	var violations []string
+
+	if strings.TrimSpace(exam.Title) == "" {
+		violations = append(violations, "title is empty")
+	}
+	if len(exam.Questions) != len(source.Questions) {
+		violations = append(violations, fmt.Sprintf("the translation needs exactly %v questions, got %v", len(source.Questions), len(exam.Questions)))
+		return violations
+	}
+
+	for i, question := range exam.Questions {
+		prefix := fmt.Sprintf("questions[%v]", i)
+
+		if strings.TrimSpace(question.Question) == "" {
+			violations = append(violations, prefix+": question is empty")
+		}
+		if strings.TrimSpace(question.Explanation) == "" {
+			violations = append(violations, prefix+": explanation is empty")
+		}
+
+		if examType == ShortAnswer && strings.TrimSpace(question.ReferenceAnswer) == "" {
+			violations = append(violations, prefix+": reference_answer is empty")
+		}
+
+		// True-false options are not taken from the translation
+		if examType == TrueFalse {
+			continue
+		}
+
+		expected := len(source.Questions[i].Options)
+		if len(question.Options) != expected {
+			violations = append(violations, fmt.Sprintf("%v: needs exactly %v options in the original order, got %v", prefix, expected, len(question.Options)))
+			continue
+		}
+		for j, option := range question.Options {
+			if strings.TrimSpace(option) == "" {
+				violations = append(violations, fmt.Sprintf("%v: options[%v] is empty", prefix, j))
+			}
+		}
+	}
+
+	return violations
+}
+
+func (interview translatableInterview) Validate(source translatableInterview) []string {
+	var violations []string
+
+	if strings.TrimSpace(interview.Title) == "" {
+		violations = append(violations, "title is empty")
+	}
+	if len(interview.Questions) != len(source.Questions) {
+		violations = append(violations, fmt.Sprintf("the translation needs exactly %v questions, got %v", len(source.Questions), len(interview.Questions)))
+		return violations
+	}
+
+	for i, question := range interview.Questions {
+		if strings.TrimSpace(question.Question) == "" {
+			violations = append(violations, fmt.Sprintf("questions[%v]: question is empty", i))
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/providers"
+)
+
+// UsageTracker meters the tokens spent on behalf of each user. Allow runs
+// once before a generation starts and can refuse it, Record runs after every
+// call to the model, repairs included.
+type UsageTracker interface {
+	Allow(ctx context.Context) error
+	Record(ctx context.Context, feature string, response providers.Response)
+}
+
+type userIdKey struct{}
+
+// Generations made with this context are billed to the user and use the
+// user's prompt variants.
+func WithUserId(ctx context.Context, userId bson.ObjectID) context.Context {
+	return context.WithValue(ctx, userIdKey{}, userId)
+}
+
+func UserIdFromContext(ctx context.Context) (bson.ObjectID, bool) {
+	userId, ok := ctx.Value(userIdKey{}).(bson.ObjectID)
+	return userId, ok && !userId.IsZero()
+}
@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError is returned when the model keeps producing output that
+// fails the semantic checks after every repair attempt.
+type ValidationError struct {
+	Feature    string
+	Violations []string
+	Attempts   int
+}
+
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("%v generation failed validation after %v attempts: %v", err.Feature, err.Attempts, strings.Join(err.Violations, "; "))
+}
+
+func ExpectedExamQuestions(difficulty string) int {
+	switch difficulty {
+	case "easy":
+		return 10
+	case "medium":
+		return 15
+	case "hard":
+		return 20
+	default:
+		return 0
+	}
+}
+
+func (exam ExamResponse) Validate(examType string, difficulty string) []string {
+	var violations []string
+
+	if strings.TrimSpace(exam.Title) == "" {
+		violations = append(violations, "title is empty")
+	}
+
+	expected := ExpectedExamQuestions(difficulty)
+	if expected > 0 && len(exam.Questions) != expected {
+		violations = append(violations, fmt.Sprintf("a %v exam needs exactly %v questions, got %v", difficulty, expected, len(exam.Questions)))
+	}
+
+	for i, question := range exam.Questions {
+		violations = append(violations, question.Validate(i, examType)...)
+	}
+
+	return violations
+}
+
+func (question ExamQuestion) Validate(index int, examType string) []string {
+	var violations []string
+	prefix := fmt.Sprintf("questions[%v]", index)
+
+	if strings.TrimSpace(question.Question) == "" {
+		violations = append(violations, prefix+": question is empty")
+	}
+	if strings.TrimSpace(question.Explanation) == "" {
+		violations = append(violations, prefix+": explanation is empty")
+	}
+
+	if examType == ShortAnswer {
+		if len(question.Options) > 0 {
+			violations = append(violations, fmt.Sprintf("%v: short-answer questions have no options, got %v", prefix, len(question.Options)))
+		}
+		if strings.TrimSpace(question.ReferenceAnswer) == "" {
+			violations = append(violations, prefix+": reference_answer is empty")
+		}
+		return violations
+	}
+
+	switch examType {
+	case TrueFalse:
+		if len(question.Options) != 2 {
+			violations = append(violations, fmt.Sprintf("%v: true-false questions need exactly 2 options, got %v", prefix, len(question.Options)))
+		} else if !isTrueFalse(question.Options) {
+			violations = append(violations, fmt.Sprintf("%v: true-false options must be \"True\" and \"False\"", prefix))
+		}
+	case MultipleChoice:
+		if len(question.Options) != 4 {
+			violations = append(violations, fmt.Sprintf("%v: multiple-choice questions need exactly 4 options, got %v", prefix, len(question.Options)))
+		}
+	case MultipleSelect:
+		if len(question.Options) != 5 {
+			violations = append(violations, fmt.Sprintf("%v: multiple-select questions need exactly 5 options, got %v", prefix, len(question.Options)))
+		}
+	case Ordering:
+		if len(question.Options) < 3 || len(question.Options) > 6 {
+			violations = append(violations, fmt.Sprintf("%v: ordering questions need between 3 and 6 items, got %v", prefix, len(question.Options)))
+		}
+	}
+
+	seen := make(map[string]bool)
+	for j, option := range question.Options {
+		option = strings.ToLower(strings.TrimSpace(option))
+		if option == "" {
+			violations = append(violations, fmt.Sprintf("%v: options[%v] is empty", prefix, j))
+		} else if seen[option] {
+			violations = append(violations, fmt.Sprintf("%v: options[%v] is duplicated", prefix, j))
+		}
+		seen[option] = true
+	}
+
+	switch examType {
+	case MultipleSelect:
+		if len(question.CorrectOptions) < 2 || len(question.CorrectOptions) > 4 {
+			violations = append(violations, fmt.Sprintf("%v: multiple-select questions need between 2 and 4 correct_options, got %v", prefix, len(question.CorrectOptions)))
+		}
+		picked := make(map[int64]bool)
+		for _, correct := range question.CorrectOptions {
+			if correct < 0 || correct >= int64(len(question.Options)) {
+				violations = append(violations, fmt.Sprintf("%v: correct_options index %v is out of range for %v options", prefix, correct, len(question.Options)))
+			} else if picked[correct] {
+				violations = append(violations, fmt.Sprintf("%v: correct_options index %v is duplicated", prefix, correct))
+			}
+			picked[correct] = true
+		}
+	case Ordering:
+		// The order the items were written in is the answer key
+	default:
+		if question.Correct < 0 || question.Correct >= int64(len(question.Options)) {
+			violations = append(violations, fmt.Sprintf("%v: correct index %v is out of range for %v options", prefix, question.Correct, len(question.Options)))
+		}
+	}
+
+	return violations
+}
+
+func isTrueFalse(options []string) bool {
+	seen := make(map[string]bool)
+	for _, option := range options {
+		seen[strings.ToLower(strings.TrimSpace(option))] = true
+	}
+
+	return len(seen) == 2 && seen["true"] && seen["false"]
+}
+
+func (interview InterviewResponse) Validate() []string {
+	var violations []string
+
+	if strings.TrimSpace(interview.Title) == "" {
+		violations = append(violations, "title is empty")
+	}
+	if len(interview.Questions) != 5 {
+		violations = append(violations, fmt.Sprintf("the interview needs exactly 5 questions, got %v", len(interview.Questions)))
+	}
+
+	for i, question := range interview.Questions {
+		if strings.TrimSpace(question.Question) == "" {
+			violations = append(violations, fmt.Sprintf("questions[%v]: question is empty", i))
+		}
+		if strings.TrimSpace(question.Type) == "" {
+			violations = append(violations, fmt.Sprintf("questions[%v]: type is empty", i))
+		}
+	}
+
+	return violations
+}
+
+func (feedback InterviewFeedbackResponse) Validate(responses int) []string {
+	var violations []string
+
+	if len(feedback.Feedbacks) != responses {
+		violations = append(violations, fmt.Sprintf("feedbacks needs exactly one entry per response (%v), got %v", responses, len(feedback.Feedbacks)))
+	}
+
+	for i, item := range feedback.Feedbacks {
+		if strings.TrimSpace(item.Feedback) == "" {
+			violations = append(violations, fmt.Sprintf("feedbacks[%v]: feedback is empty", i))
+		}
+		if item.Score < 1 || item.Score > 10 {
+			violations = append(violations, fmt.Sprintf("feedbacks[%v]: score %v must be between 1 and 10", i, item.Score))
+		}
+	}
+
+	if strings.TrimSpace(feedback.Analysis) == "" {
+		violations = append(violations, "analysis is empty")
+	}
+	if len(feedback.Strengths) == 0 {
+		violations = append(violations, "strengths is empty")
+	}
+	if len(feedback.AreasToImprove) == 0 {
+		violations = append(violations, "areas_to_improve is empty")
+	}
+
+	return violations
+}
+
+func (grades ShortAnswerGrades) Validate(answers int) []string {
+	var violations []string
+
+	if len(grades.Grades) != answers {
+		violations = append(violations, fmt.Sprintf("grades needs exactly one entry per answer (%v), got %v", answers, len(grades.Grades)))
+	}
+
+	for i, grade := range grades.Grades {
+		if grade.Score < 0 || grade.Score > 10 {
+			violations = append(violations, fmt.Sprintf("grades[%v]: score %v must be between 0 and 10", i, grade.Score))
+		}
+		if strings.TrimSpace(grade.Feedback) == "" {
+			violations = append(violations, fmt.Sprintf("grades[%v]: feedback is empty", i))
+		}
+	}
+
+	return violations
+}
+
+func (analysis ResumeAnalyzerResponse) Validate() []string {
+	var violations []string
+
+	if strings.TrimSpace(analysis.Title) == "" {
+		violations = append(violations, "title is empty")
+	}
+	if analysis.OverallScore < 1 || analysis.OverallScore > 100 {
+		violations = append(violations, fmt.Sprintf("overall_score %v must be between 1 and 100", analysis.OverallScore))
+	}
+	if strings.TrimSpace(analysis.AnalysisSummary) == "" {
+		violations = append(violations, "analysis_summary is empty")
+	}
+	if analysis.Metrics.AtsMatchScore < 1 || analysis.Metrics.AtsMatchScore > 100 {
+		violations = append(violations, fmt.Sprintf("metrics.ats_match_score %v must be between 1 and 100", analysis.Metrics.AtsMatchScore))
+	}
+	if analysis.Metrics.ClarityScore < 1 || analysis.Metrics.ClarityScore > 10 {
+		violations = append(violations, fmt.Sprintf("metrics.clarity_score %v must be between 1 and 10", analysis.Metrics.ClarityScore))
+	}
+	if analysis.Metrics.GrammarIssues < 0 {
+		violations = append(violations, "metrics.grammar_issues cannot be negative")
+	}
+
+	return violations
+}
+
+func (analysis QuestionAnalysisResponse) Validate() []string {
+	var violations []string
+
+	switch analysis.Type {
+	case "Behavioral", "Technical", "HR", "Situational", "Other":
+	default:
+		violations = append(violations, fmt.Sprintf("type %q must be one of Behavioral, Technical, HR, Situational or Other", analysis.Type))
+	}
+
+	if ExpectedExamQuestions(analysis.Difficulty) == 0 {
+		violations = append(violations, fmt.Sprintf("difficulty %q must be one of easy, medium or hard", analysis.Difficulty))
+	}
+	if strings.TrimSpace(analysis.Explanation) == "" {
+		violations = append(violations, "explanation is empty")
+	}
+	if len(analysis.IdealAnswer.KeyPoints) == 0 {
+		violations = append(violations, "ideal_answer.key_points is empty")
+	}
+	if strings.TrimSpace(analysis.IdealAnswer.Example) == "" {
+		violations = append(violations, "ideal_answer.example is empty")
+	}
+
+	return violations
+}
+
+func (modules ModuleResponse) Validate() []string {
+	var violations []string
+
+	if len(modules.Modules) < 10 || len(modules.Modules) > 12 {
+		violations = append(violations, fmt.Sprintf("there must be between 10 and 12 modules, got %v", len(modules.Modules)))
+	}
+
+	// The final challenge is the module with the highest order, its title is
+	// translated with the rest of the path
+	orders := make(map[int64]bool)
+	for i, module := range modules.Modules {
+		if strings.TrimSpace(module.Title) == "" {
+			violations = append(violations, fmt.Sprintf("modules[%v]: title is empty", i))
+		}
+		if orders[module.Order] {
+			violations = append(violations, fmt.Sprintf("modules[%v]: order %v is duplicated", i, module.Order))
+		}
+		orders[module.Order] = true
+	}
+
+	return violations
+}
+
+func (steps StepsResponse) Validate() []string {
+	var violations []string
+
+	if len(steps.Steps) < 8 || len(steps.Steps) > 10 {
+		violations = append(violations, fmt.Sprintf("there must be between 8 and 10 steps, got %v", len(steps.Steps)))
+	}
+
+	orders := make(map[int64]bool)
+	for i, step := range steps.Steps {
+		if strings.TrimSpace(step.Title) == "" {
+			violations = append(violations, fmt.Sprintf("steps[%v]: title is empty", i))
+		}
+		if strings.TrimSpace(step.Type) == "" {
+			violations = append(violations, fmt.Sprintf("steps[%v]: type is empty", i))
+		}
+		if orders[step.Order] {
+			violations = append(violations, fmt.Sprintf("steps[%v]: order %v is duplicated", i, step.Order))
+		}
+		orders[step.Order] = true
+	}
+
+	return violations
+}
+
+func (lesson LessonResponse) Validate() []string {
+	var violations []string
+
+	if strings.TrimSpace(lesson.Summary) == "" {
+		violations = append(violations, "summary is empty")
+	}
+	if len(lesson.Sections) < 3 || len(lesson.Sections) > 5 {
+		violations = append(violations, fmt.Sprintf("there must be between 3 and 5 sections, got %v", len(lesson.Sections)))
+	}
+	for i, section := range lesson.Sections {
+		if strings.TrimSpace(section.Heading) == "" || strings.TrimSpace(section.Content) == "" {
+			violations = append(violations, fmt.Sprintf("sections[%v]: heading and content are required", i))
+		}
+	}
+	if len(lesson.KeyTakeaways) == 0 {
+		violations = append(violations, "key_takeaways is empty")
+	}
+
+	return violations
+}
@@ -0,0 +1,337 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/internal"
+	"prepai.app/models"
+)
+
+type ExamPayload struct {
+	Subject    string `bson:"subject"`
+	Difficulty string `bson:"difficulty"`
+	Type       string `bson:"type"`
+	Language   string `bson:"language"`
+	TimeLimit  int64  `bson:"time_limit"`
+}
+
+type InterviewPayload struct {
+	JobRole  string   `bson:"job_role"`
+	JobLevel string   `bson:"job_level"`
+	Topics   []string `bson:"topics"`
+	Language string   `bson:"language"`
+}
+
+type RegeneratePayload struct {
+	Id bson.ObjectID `bson:"id"`
+}
+
+// The translation is saved as a new document, the original is kept.
+type TranslatePayload struct {
+	Id       bson.ObjectID `bson:"id"`
+	Language string        `bson:"language"`
+}
+
+type QuestionPayload struct {
+	Question string `bson:"question"`
+	Language string `bson:"language"`
+}
+
+type ResumePayload struct {
+	FileUrl        string `bson:"file_url"`
+	Resume         []byte `bson:"resume"`
+	JobDescription string `bson:"job_description"`
+	Language       string `bson:"language"`
+}
+
+func createExam(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error) {
+	var payload ExamPayload
+	err := bson.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	result, err := generator.GenerateExam(internal.WithLanguage(ctx, payload.Language), payload.Subject, payload.Difficulty, payload.Type)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	exam := models.Exam{
+		Title:            result.Title,
+		Subject:          payload.Subject,
+		Difficulty:       payload.Difficulty,
+		Type:             payload.Type,
+		Language:         payload.Language,
+		TimeLimit:        payload.TimeLimit,
+		Questions:        result.Questions,
+		Prompt:           &result.Prompt,
+		ShuffleSeed:      result.Seed,
+		CorrectPositions: result.CorrectPositions(),
+		UserId:           job.UserId,
+	}
+
+	err = exam.Save()
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	return models.ContentExam, exam.Id, nil
+}
+
+func regenerateExam(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error) {
+	var payload RegeneratePayload
+	err := bson.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	exam, err := models.GetExamById(payload.Id, true)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	if exam.UserId != job.UserId {
+		return "", bson.NilObjectID, errors.New("exam does not belong to you")
+	}
+
+	// The user asked for different content, a cached exam would be the same
+	result, err := generator.GenerateExam(internal.WithLanguage(internal.WithoutCache(ctx), exam.Language), exam.Subject, exam.Difficulty, exam.Type)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	exam.Title = result.Title
+	exam.Questions = result.Questions
+	exam.Prompt = &result.Prompt
+	exam.ShuffleSeed = result.Seed
+	exam.CorrectPositions = result.CorrectPositions()
+
+	err = exam.SetQuestions()
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	return models.ContentExam, exam.Id, nil
+}
+
+func createInterview(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error) {
+	var payload InterviewPayload
+	err := bson.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	result, err := generator.GenerateInterview(internal.WithLanguage(ctx, payload.Language), payload.JobRole, payload.JobLevel, payload.Topics)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	interview := models.Interview{
+		Title:     result.Title,
+		JobRole:   payload.JobRole,
+		JobLevel:  payload.JobLevel,
+		Topics:    payload.Topics,
+		Language:  payload.Language,
+		Questions: result.Questions,
+		Prompt:    &result.Prompt,
+		UserId:    job.UserId,
+	}
+
+	err = interview.Save()
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	return models.ContentInterview, interview.Id, nil
+}
+
+func regenerateInterview(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error) {
+	var payload RegeneratePayload
+	err := bson.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	interview, err := models.GetInterviewById(payload.Id)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	if interview.UserId != job.UserId {
+		return "", bson.NilObjectID, errors.New("interview does not belong to you")
+	}
+
+	// The user asked for different content, a cached interview would be the same
+	result, err := generator.GenerateInterview(internal.WithLanguage(internal.WithoutCache(ctx), interview.Language), interview.JobRole, interview.JobLevel, interview.Topics)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	interview.Title = result.Title
+	interview.Questions = result.Questions
+	interview.Prompt = &result.Prompt
+	interview.Regenerations++
+
+	err = interview.Update()
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	return models.ContentInterview, interview.Id, nil
+}
+
+func translateExam(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error) {
+	var payload TranslatePayload
+	err := bson.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	exam, err := models.GetExamById(payload.Id, true)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	if exam.UserId != job.UserId {
+		return "", bson.NilObjectID, errors.New("exam does not belong to you")
+	}
+
+	result, err := generator.TranslateExam(ctx, internal.ExamResponse{
+		Title:     exam.Title,
+		Questions: exam.Questions,
+		Seed:      exam.ShuffleSeed,
+	}, exam.Type, payload.Language)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	// Options keep their order, so the answer key carries over. Positions are
+	// left to the source exam, otherwise the stats would count them twice.
+	translated := models.Exam{
+		Title:       result.Title,
+		Subject:     exam.Subject,
+		Difficulty:  exam.Difficulty,
+		Type:        exam.Type,
+		Language:    payload.Language,
+		TimeLimit:   exam.TimeLimit,
+		Questions:   result.Questions,
+		Prompt:      &result.Prompt,
+		ShuffleSeed: exam.ShuffleSeed,
+		UserId:      job.UserId,
+	}
+
+	err = translated.Save()
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	return models.ContentExam, translated.Id, nil
+}
+
+func translateInterview(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error) {
+	var payload TranslatePayload
+	err := bson.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	interview, err := models.GetInterviewById(payload.Id)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	if interview.UserId != job.UserId {
+		return "", bson.NilObjectID, errors.New("interview does not belong to you")
+	}
+
+	result, err := generator.TranslateInterview(ctx, internal.InterviewResponse{
+		Title:     interview.Title,
+		Questions: interview.Questions,
+	}, payload.Language)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	translated := models.Interview{
+		Title:     result.Title,
+		JobRole:   interview.JobRole,
+		JobLevel:  interview.JobLevel,
+		Topics:    interview.Topics,
+		Language:  payload.Language,
+		Questions: result.Questions,
+		Prompt:    &result.Prompt,
+		UserId:    job.UserId,
+	}
+
+	err = translated.Save()
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	return models.ContentInterview, translated.Id, nil
+}
+
+func createQuestion(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error) {
+	var payload QuestionPayload
+	err := bson.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	result, err := generator.GenerateQuestionAnalysis(internal.WithLanguage(ctx, payload.Language), payload.Question)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	question := models.Question{
+		Question:       payload.Question,
+		Type:           result.Type,
+		Difficulty:     result.Difficulty,
+		Explanation:    result.Explanation,
+		ExpectedLength: result.ExpectedLength,
+		IdealAnswer:    result.IdealAnswer,
+		Language:       payload.Language,
+		UserId:         job.UserId,
+	}
+
+	err = question.Save()
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	return models.ContentQuestion, question.Id, nil
+}
+
+func createResume(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error) {
+	var payload ResumePayload
+	err := bson.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	result, err := generator.ResumeAnalyzer(internal.WithLanguage(ctx, payload.Language), payload.Resume, payload.JobDescription)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	resume := models.Resume{
+		FileUrl:                payload.FileUrl,
+		Title:                  result.Title,
+		OverallScore:           result.OverallScore,
+		AnalysisSummary:        result.AnalysisSummary,
+		ImprovementSuggestions: result.ImprovementSuggestions,
+		Metrics:                result.Metrics,
+		Language:               payload.Language,
+		Prompt:                 &result.Prompt,
+		UserId:                 job.UserId,
+	}
+
+	err = resume.Save()
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+
+	return models.ContentResume, resume.Id, nil
+}
@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"prepai.app/configs"
+	"prepai.app/internal"
+	"prepai.app/models"
+)
+
+// StartSweeper closes the exam attempts left open past their deadline, it
+// stops when ctx is cancelled. Short answers are graded with generator.
+func StartSweeper(ctx context.Context, generator *internal.Generator, timing configs.ExamTimingConfig) {
+	go func() {
+		ticker := time.NewTicker(timing.SweepInterval)
+		defer ticker.Stop()
+
+		for {
+			sweepExamAttempts(ctx, generator, timing.Grace)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Abandoned attempts are graded with the answers saved before the deadline.
+// Attempts that cannot be graded now are left for the next sweep.
+func sweepExamAttempts(ctx context.Context, generator *internal.Generator, grace time.Duration) {
+	now := time.Now()
+
+	attempts, err := models.GetOverdueExamAttempts(now.Add(-grace))
+	if err != nil {
+		log.Printf("failed to fetch overdue exam attempts: %v", err)
+		return
+	}
+
+	for _, attempt := range attempts {
+		exam, err := models.GetExamById(attempt.ExamId, true)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// The exam was deleted, there is nothing to grade the attempt against
+			_, err = attempt.Close(models.AttemptExpired, now)
+			if err != nil {
+				log.Printf("failed to close exam attempt %v: %v", attempt.Id.Hex(), err)
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("failed to fetch exam %v of attempt %v: %v", attempt.ExamId.Hex(), attempt.Id.Hex(), err)
+			continue
+		}
+
+		// Answers saved for a previous revision of the exam match none of its
+		// questions, so they do not count
+		gradeCtx := internal.WithLanguage(internal.WithUserId(ctx, attempt.UserId), exam.Language)
+		grades, err := generator.GradeExam(gradeCtx, exam.Questions, attempt.Responses)
+		if err != nil {
+			log.Printf("failed to grade exam attempt %v: %v", attempt.Id.Hex(), err)
+			continue
+		}
+
+		attempt.Grade(exam.Questions, attempt.Responses, grades)
+
+		// The user may have submitted it meanwhile
+		closed, err := attempt.Close(models.AttemptExpired, now)
+		if err != nil {
+			log.Printf("failed to close exam attempt %v: %v", attempt.Id.Hex(), err)
+			continue
+		}
+		if !closed {
+			continue
+		}
+
+		err = models.UpdateExamScores(exam)
+		if err != nil {
+			log.Printf("failed to update scores of exam %v: %v", exam.Id.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/internal"
+	"prepai.app/models"
+)
+
+const (
+	pollInterval = 2 * time.Second
+	jobTimeout   = 5 * time.Minute
+	staleAfter   = 10 * time.Minute
+	maxAttempts  = 3
+)
+
+// A handler runs one job and returns the type and id of the document it
+// produced.
+type handler func(ctx context.Context, generator *internal.Generator, job *models.Job) (string, bson.ObjectID, error)
+
+var handlers = map[string]handler{
+	models.JobCreateExam:          createExam,
+	models.JobRegenerateExam:      regenerateExam,
+	models.JobCreateInterview:     createInterview,
+	models.JobRegenerateInterview: regenerateInterview,
+	models.JobCreateQuestion:      createQuestion,
+	models.JobCreateResume:        createResume,
+	models.JobTranslateExam:       translateExam,
+	models.JobTranslateInterview:  translateInterview,
+}
+
+var wake = make(chan struct{}, 1)
+
+// Enqueue stores the job and wakes up an idle worker.
+func Enqueue(jobType string, userId bson.ObjectID, payload any) (*models.Job, error) {
+	job, err := models.NewJob(jobType, userId, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = job.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Start launches the worker goroutines, they stop when ctx is cancelled.
+func Start(ctx context.Context, generator *internal.Generator, workers int) {
+	for i := 0; i < workers; i++ {
+		go work(ctx, generator)
+	}
+}
+
+func work(ctx context.Context, generator *internal.Generator) {
+	for {
+		job, err := models.ClaimNextJob(staleAfter, maxAttempts)
+		if err != nil {
+			log.Printf("failed to claim job: %v", err)
+		}
+
+		if job == nil {
+			err = models.FailExhaustedJobs(staleAfter, maxAttempts)
+			if err != nil {
+				log.Printf("failed to clean up jobs: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		run(ctx, generator, job)
+	}
+}
+
+func run(ctx context.Context, generator *internal.Generator, job *models.Job) {
+	handle, ok := handlers[job.Type]
+	if !ok {
+		fail(job, fmt.Errorf("unknown job type %v", job.Type))
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(internal.WithUserId(ctx, job.UserId), jobTimeout)
+	defer cancel()
+
+	resultType, resultId, err := runSafely(jobCtx, handle, generator, job)
+	if err != nil {
+		fail(job, err)
+		return
+	}
+
+	err = job.Complete(resultType, resultId)
+	if err != nil {
+		log.Printf("failed to complete job %v: %v", job.Id.Hex(), err)
+	}
+}
+
+func runSafely(ctx context.Context, handle handler, generator *internal.Generator, job *models.Job) (resultType string, resultId bson.ObjectID, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+
+	return handle(ctx, generator, job)
+}
+
+func fail(job *models.Job, err error) {
+	var validationErr *internal.ValidationError
+	reason := err.Error()
+	if errors.As(err, &validationErr) {
+		reason = "the generated content was invalid, try again later"
+	}
+
+	err = job.Fail(reason)
+	if err != nil {
+		log.Printf("failed to mark job %v as failed: %v", job.Id.Hex(), err)
+	}
+}
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"context"
+	"log"
+
 	"github.com/gin-gonic/gin"
 	"prepai.app/configs"
+	"prepai.app/controllers"
+	"prepai.app/internal"
+	"prepai.app/jobs"
+	"prepai.app/models"
+	"prepai.app/prompts"
+	"prepai.app/providers"
 	"prepai.app/routes"
 )
 
@@ -11,6 +20,31 @@ func main() {
 	configs.ConnectDB()
 	configs.InitDatabase()
 
+	// Setting up AI provider
+	provider, err := providers.NewFromEnv()
+	if err != nil {
+		log.Fatalf("failed to set up ai provider: %v", err)
+	}
+	registry, err := prompts.NewFromEnv()
+	if err != nil {
+		log.Fatalf("failed to load prompts: %v", err)
+	}
+	generation, err := providers.WithCassetteFromEnv(provider)
+	if err != nil {
+		log.Fatalf("failed to set up cassette: %v", err)
+	}
+	controllers.Generator = internal.NewGenerator(generation)
+	controllers.Generator.Prompts = registry
+	controllers.Generator.MaxRepairs = configs.GetMaxRepairs()
+	controllers.Generator.ExamChunkSize = configs.GetExamChunkSize()
+	controllers.Generator.Usage = models.UsageTracker{}
+	controllers.Generator.Cache = models.GenerationCache{}
+	controllers.Limiter = provider.Limiter()
+
+	// Background generation workers
+	jobs.Start(context.Background(), controllers.Generator, configs.GetJobWorkers())
+	jobs.StartSweeper(context.Background(), controllers.Generator, configs.GetExamTimingConfig())
+
 	server := gin.Default()
 
 	// Routes
@@ -20,6 +54,10 @@ func main() {
 	routes.ExamRoute(server)
 	routes.QuestionRoute(server)
 	routes.ResumeRoute(server)
+	routes.LearningPathRoute(server)
+	routes.ActivityRoute(server)
+	routes.JobRoute(server)
+	routes.AdminRoute(server)
 
 	server.Run(":8080")
 }
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/configs"
+	"prepai.app/models"
+)
+
+// RequireAdmin must run after Authenticate.
+func RequireAdmin(context *gin.Context) {
+	userId, ok := context.MustGet("userId").(bson.ObjectID)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "Not authorized",
+		})
+		return
+	}
+
+	user, err := models.GetUser(userId)
+	if err != nil || !slices.Contains(configs.GetAdminEmails(), strings.ToLower(user.Email)) {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"message": "Admin access required",
+		})
+		return
+	}
+
+	context.Next()
+}
@@ -0,0 +1,265 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"prepai.app/configs"
+)
+
+const (
+	ActivityLocked     = "locked"
+	ActivityAvailable  = "available"
+	ActivityInProgress = "in_progress"
+	ActivityCompleted  = "completed"
+)
+
+const (
+	ContentExam      = "exam"
+	ContentInterview = "interview"
+	ContentQuestion  = "question"
+	ContentLesson    = "lesson"
+	ContentResume    = "resume"
+)
+
+type Activity struct {
+	Id          bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	Title       string        `json:"title" bson:"title,omitempty"`
+	ContentType string        `json:"content_type" bson:"content_type"`
+	ContentId   bson.ObjectID `json:"content_id" bson:"content_id,omitempty"`
+	Status      string        `json:"status" bson:"status"`
+	Difficulty  string        `json:"difficulty" bson:"difficulty,omitempty"`
+	PathId      bson.ObjectID `json:"path_id" bson:"path_id,omitempty"`
+	ModuleOrder int64         `json:"module_order" bson:"module_order,omitempty"`
+	StepOrder   int64         `json:"step_order" bson:"step_order,omitempty"`
+	UserId      bson.ObjectID `json:"user_id" bson:"user_id"`
+}
+
+// Maps the free text type generated for a step ("mock exam", "open question"...)
+// to the kind of content that carries it out.
+func ContentTypeFromStep(stepType string) string {
+	stepType = strings.ToLower(stepType)
+
+	switch {
+	case strings.Contains(stepType, "exam"), strings.Contains(stepType, "quiz"):
+		return ContentExam
+	case strings.Contains(stepType, "interview"):
+		return ContentInterview
+	case strings.Contains(stepType, "question"):
+		return ContentQuestion
+	default:
+		return ContentLesson
+	}
+}
+
+// Lessons and open questions have nothing to submit, the user marks them as
+// done. Exams and interviews are completed by their attempts.
+func (activity Activity) CompletedByUser() bool {
+	return activity.ContentType == ContentLesson || activity.ContentType == ContentQuestion
+}
+
+func GetAllUserActivities(userId bson.ObjectID, status string) ([]Activity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+
+	filter := bson.M{"user_id": userId}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Activity
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func GetActivitiesByPathId(pathId bson.ObjectID) ([]Activity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+	opts := options.Find().SetSort(bson.D{
+		{Key: "module_order", Value: 1},
+		{Key: "step_order", Value: 1},
+	})
+
+	cursor, err := collection.Find(ctx, bson.M{"path_id": pathId}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Activity
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func GetActivityById(activityId bson.ObjectID) (*Activity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+
+	var activity Activity
+	err := collection.FindOne(ctx, bson.M{"_id": activityId}).Decode(&activity)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	return &activity, nil
+}
+
+func SaveActivities(activities []Activity) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+	result, err := collection.InsertMany(ctx, activities)
+	if err != nil {
+		return err
+	}
+
+	for i, insertedId := range result.InsertedIDs {
+		id, ok := insertedId.(bson.ObjectID)
+		if !ok {
+			return errors.New("failed to get document id")
+		}
+		activities[i].Id = id
+	}
+
+	return nil
+}
+
+func DeleteActivitiesByPathId(pathId bson.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+	_, err := collection.DeleteMany(ctx, bson.M{"path_id": pathId})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DeleteActivities(activities []Activity) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ids := make([]bson.ObjectID, len(activities))
+	for i := range activities {
+		ids[i] = activities[i].Id
+	}
+
+	collection := configs.GetCollection("activities")
+	_, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (activity *Activity) Save() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+	result, err := collection.InsertOne(ctx, activity)
+	if err != nil {
+		return err
+	}
+
+	id, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return errors.New("failed to get document id")
+	}
+
+	activity.Id = id
+	return nil
+}
+
+func (activity Activity) Update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+	update := bson.M{
+		"$set": bson.M{
+			"status": activity.Status,
+		},
+	}
+
+	_, err := collection.UpdateByID(ctx, activity.Id, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Links the generated content to the activity. It only succeeds when no other
+// request linked content first, returning false when the activity was taken.
+func (activity *Activity) SetContent(contentId bson.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+	filter := bson.M{
+		"_id": activity.Id,
+		"$or": []bson.M{
+			{"content_id": bson.M{"$exists": false}},
+			{"content_id": bson.NilObjectID},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"content_id": contentId,
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	activity.ContentId = contentId
+	return true, nil
+}
+
+func (activity Activity) Delete() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("activities")
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": activity.Id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
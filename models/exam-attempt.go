@@ -3,38 +3,205 @@ package models
 import (
 	"context"
 	"errors"
+	"math"
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"prepai.app/configs"
+	"prepai.app/internal"
 )
 
+const (
+	AttemptInProgress = "in_progress"
+	AttemptSubmitted  = "submitted"
+	AttemptExpired    = "expired"
+)
+
+// The answer key of the question is copied along with the answer. Credit
+// goes from 0 to 1, short answers get feedback from the model.
 type ExamAnswer struct {
-	Question    string `json:"question"`
-	Answer      int64  `json:"answer"`
-	Correct     int64  `json:"correct"`
-	Explanation string `json:"explanation"`
+	QuestionId      string          `json:"question_id" bson:"question_id,omitempty"`
+	Type            string          `json:"type,omitempty" bson:"type,omitempty"`
+	Question        string          `json:"question" bson:"question"`
+	Answer          internal.Answer `json:"answer" bson:"answer"`
+	Correct         int64           `json:"correct" bson:"correct"`
+	CorrectOptions  []int64         `json:"correct_options,omitempty" bson:"correct_options,omitempty"`
+	CorrectOrder    []int64         `json:"correct_order,omitempty" bson:"correct_order,omitempty"`
+	ReferenceAnswer string          `json:"reference_answer,omitempty" bson:"reference_answer,omitempty"`
+	Credit          float64         `json:"credit" bson:"credit"`
+	Feedback        string          `json:"feedback,omitempty" bson:"feedback,omitempty"`
+	Explanation     string          `json:"explanation" bson:"explanation"`
 }
 
+// CreatedAt is when the attempt started on the server, Time is worked out
+// from it when the attempt is closed. Deadline is missing for untimed exams.
+// While in progress, Responses holds the answers saved so far keyed by
+// question id and Current the question the user is on. Revision and
+// QuestionIds pin the exam the attempt started on, answers for another one
+// are not accepted.
 type ExamAttempt struct {
-	Id      bson.ObjectID `json:"id" bson:"_id,omitempty"`
-	Time    int64         `json:"time" bson:"time,omitempty"`
-	Score   float64       `json:"score" bson:"score,omitempty"`
-	Answers []ExamAnswer  `json:"answers" bson:"answers,omitempty"`
-	Passed  bool          `json:"passed" bson:"passed,omitempty"`
-	UserId  bson.ObjectID `json:"user_id" bson:"user_id"`
-	ExamId  bson.ObjectID `json:"exam_id" bson:"exam_id"`
+	Id          bson.ObjectID              `json:"id" bson:"_id,omitempty"`
+	Status      string                     `json:"status" bson:"status,omitempty"`
+	Time        int64                      `json:"time" bson:"time,omitempty"`
+	Score       float64                    `json:"score" bson:"score,omitempty"`
+	Answers     []ExamAnswer               `json:"answers" bson:"answers,omitempty"`
+	Responses   map[string]internal.Answer `json:"responses,omitempty" bson:"responses,omitempty"`
+	Current     int64                      `json:"current" bson:"current,omitempty"`
+	Revision    int64                      `json:"revision" bson:"revision,omitempty"`
+	QuestionIds []string                   `json:"question_ids,omitempty" bson:"question_ids,omitempty"`
+	Passed      bool                       `json:"passed" bson:"passed,omitempty"`
+	TimeLimit   int64                      `json:"time_limit,omitempty" bson:"time_limit,omitempty"`
+	Remaining   int64                      `json:"remaining,omitempty" bson:"-"`
+	CreatedAt   time.Time                  `json:"created_at" bson:"created_at"`
+	Deadline    time.Time                  `json:"deadline,omitempty" bson:"deadline,omitempty"`
+	SubmittedAt time.Time                  `json:"submitted_at,omitempty" bson:"submitted_at,omitempty"`
+	UserId      bson.ObjectID              `json:"user_id" bson:"user_id"`
+	ExamId      bson.ObjectID              `json:"exam_id" bson:"exam_id"`
+}
+
+// Starts an attempt of the exam now, limited to duration when it is not zero.
+func NewExamAttempt(exam *Exam, userId bson.ObjectID, duration time.Duration) ExamAttempt {
+	attempt := ExamAttempt{
+		Status:      AttemptInProgress,
+		Revision:    exam.Regenerations,
+		QuestionIds: exam.QuestionIds(),
+		CreatedAt:   time.Now(),
+		UserId:      userId,
+		ExamId:      exam.Id,
+	}
+	if duration > 0 {
+		attempt.TimeLimit = int64(duration / time.Second)
+		attempt.Deadline = attempt.CreatedAt.Add(duration)
+	}
+
+	return attempt
+}
+
+// Attempts from before statuses were stored are open until submitted, and
+// the ones submitted back then only have their answers to show for it.
+func (attempt ExamAttempt) Open() bool {
+	if attempt.Status != "" {
+		return attempt.Status == AttemptInProgress
+	}
+
+	return attempt.SubmittedAt.IsZero() && len(attempt.Answers) == 0
+}
+
+// Matches the attempt while it is open, see Open.
+func openAttemptFilter(attemptId bson.ObjectID) bson.M {
+	return bson.M{
+		"_id":          attemptId,
+		"submitted_at": bson.M{"$exists": false},
+		"answers":      bson.M{"$exists": false},
+	}
+}
+
+// Matches the submitted attempts, including the ones submitted before
+// submitted_at was stored.
+var submittedAttempts = bson.M{"$or": bson.A{
+	bson.M{"submitted_at": bson.M{"$exists": true}},
+	bson.M{"answers": bson.M{"$exists": true}},
+}}
+
+// Whether the exam is still the revision the attempt started on. Attempts
+// from before questions had ids are not pinned to one.
+func (attempt ExamAttempt) Matches(exam *Exam) bool {
+	if len(attempt.QuestionIds) == 0 {
+		return true
+	}
+
+	return attempt.Revision == exam.Regenerations && slices.Equal(attempt.QuestionIds, exam.QuestionIds())
+}
+
+// Works out the seconds left to answer, the server clock is the one that
+// counts so clients show this instead of their own.
+func (attempt *ExamAttempt) SetRemaining(now time.Time) {
+	attempt.Remaining = 0
+	if attempt.Open() && !attempt.Deadline.IsZero() && now.Before(attempt.Deadline) {
+		attempt.Remaining = int64(attempt.Deadline.Sub(now) / time.Second)
+	}
+}
+
+// Whether the time to answer ran out, grace included.
+func (attempt ExamAttempt) Overdue(now time.Time, grace time.Duration) bool {
+	return !attempt.Deadline.IsZero() && now.After(attempt.Deadline.Add(grace))
+}
+
+// Every attempt of the exam, the latest first. Answers are left out.
+func GetExamAttempts(examId bson.ObjectID) ([]ExamAttempt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("examAttempts")
+	opts := options.Find().
+		SetProjection(bson.M{"answers": 0}).
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	cursor, err := collection.Find(ctx, bson.M{"exam_id": examId}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []ExamAttempt{}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func GetExamAttemptById(attemptId bson.ObjectID) (*ExamAttempt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("examAttempts")
+
+	var attempt ExamAttempt
+	err := collection.FindOne(ctx, bson.M{"_id": attemptId}).Decode(&attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// Open attempts whose deadline passed before the time.
+func GetOverdueExamAttempts(before time.Time) ([]ExamAttempt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("examAttempts")
+	filter := bson.M{
+		"status":   AttemptInProgress,
+		"deadline": bson.M{"$lt": before},
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ExamAttempt
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
 }
 
+// The latest attempt of the exam.
 func GetAttemptByExamId(examId bson.ObjectID) (*ExamAttempt, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	collection := configs.GetCollection("examAttempts")
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
 
 	var attempt ExamAttempt
-	err := collection.FindOne(ctx, bson.M{"exam_id": examId}).Decode(&attempt)
+	err := collection.FindOne(ctx, bson.M{"exam_id": examId}, opts).Decode(&attempt)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, err
@@ -64,24 +231,186 @@ func (attempt *ExamAttempt) Save() error {
 	return nil
 }
 
-func (attempt ExamAttempt) Update() error {
+// Stores answers of the attempt while it is in progress, an empty answer
+// clears the saved one. Returns false when the attempt was closed meanwhile.
+func (attempt *ExamAttempt) SaveResponses(responses map[string]internal.Answer, current *int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{}
+	unset := bson.M{}
+	for key, response := range responses {
+		if response.Empty() {
+			unset["responses."+key] = ""
+		} else {
+			set["responses."+key] = response
+		}
+	}
+	if current != nil {
+		set["current"] = *current
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return attempt.Open(), nil
+	}
+
+	collection := configs.GetCollection("examAttempts")
+	filter := openAttemptFilter(attempt.Id)
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// Records the responses keyed by question id with the grade of each
+// question, in the order of the questions. The score is out of 10 and 70% of
+// the credit passes.
+func (attempt *ExamAttempt) Grade(questions []internal.ExamQuestion, responses map[string]internal.Answer, grades []internal.AnswerGrade) {
+	answers := make([]ExamAnswer, len(questions))
+	credit := 0.0
+
+	for i, question := range questions {
+		response := responses[question.Id]
+		if response.Empty() {
+			response = question.NoAnswer()
+		}
+		credit += grades[i].Credit
+
+		answers[i] = ExamAnswer{
+			QuestionId:      question.Id,
+			Type:            question.Type,
+			Question:        question.Question,
+			Answer:          response,
+			Correct:         question.Correct,
+			CorrectOptions:  question.CorrectOptions,
+			CorrectOrder:    question.CorrectOrder,
+			ReferenceAnswer: question.ReferenceAnswer,
+			Credit:          grades[i].Credit,
+			Feedback:        grades[i].Feedback,
+			Explanation:     question.Explanation,
+		}
+	}
+
+	ratio := 0.0
+	if len(questions) > 0 {
+		ratio = credit / float64(len(questions))
+	}
+
+	attempt.Answers = answers
+	attempt.Score = math.Round(ratio*100) / 10
+	attempt.Passed = ratio >= 0.7
+}
+
+// Stores the graded attempt with the status unless it was closed in the
+// meantime, in which case it returns false. The time taken stops at the
+// deadline.
+func (attempt *ExamAttempt) Close(status string, now time.Time) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	attempt.Status = status
+	attempt.SubmittedAt = now
+	if !attempt.CreatedAt.IsZero() {
+		end := now
+		if !attempt.Deadline.IsZero() && end.After(attempt.Deadline) {
+			end = attempt.Deadline
+		}
+		attempt.Time = int64(end.Sub(attempt.CreatedAt) / time.Second)
+	}
+
 	collection := configs.GetCollection("examAttempts")
-	update := bson.M{
-		"$set": bson.M{
-			"answers": attempt.Answers,
-			"passed":  attempt.Passed,
-			"score":   attempt.Score,
-			"time":    attempt.Time,
-		},
+	filter := openAttemptFilter(attempt.Id)
+	set := bson.M{
+		"status":       attempt.Status,
+		"passed":       attempt.Passed,
+		"score":        attempt.Score,
+		"time":         attempt.Time,
+		"submitted_at": attempt.SubmittedAt,
+	}
+	// Attempts whose exam is gone are closed without answers
+	if len(attempt.Answers) > 0 {
+		set["answers"] = attempt.Answers
+	}
+	update := bson.M{"$set": set}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
 	}
 
-	_, err := collection.UpdateByID(ctx, attempt.Id, update)
+	return result.MatchedCount == 1, nil
+}
+
+// Refreshes the summary of the submitted attempts stored on the exam.
+func UpdateExamScores(exam *Exam) error {
+	scores, err := GetExamScores(exam.Id)
+	if err != nil {
+		return err
+	}
+
+	err = exam.SetScores(*scores)
 	if err != nil {
 		return err
 	}
 
+	exam.Scores = scores
 	return nil
 }
+
+// Scores of the submitted attempts of an exam, out of 10.
+type ExamScores struct {
+	Attempts     int64   `json:"attempts" bson:"attempts"`
+	BestScore    float64 `json:"best_score" bson:"best_score"`
+	LatestScore  float64 `json:"latest_score" bson:"latest_score"`
+	AverageScore float64 `json:"average_score" bson:"average_score"`
+	Passed       bool    `json:"-" bson:"passed"`
+}
+
+func GetExamScores(examId bson.ObjectID) (*ExamScores, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"exam_id": examId}},
+		{"$match": submittedAttempts},
+		// Attempts without submitted_at come first, they are the oldest
+		{"$sort": bson.D{{Key: "submitted_at", Value: 1}, {Key: "_id", Value: 1}}},
+		// A score of 0 is not stored
+		{"$set": bson.M{"score": bson.M{"$ifNull": bson.A{"$score", 0}}}},
+		{"$group": bson.M{
+			"_id":           nil,
+			"attempts":      bson.M{"$sum": 1},
+			"best_score":    bson.M{"$max": "$score"},
+			"latest_score":  bson.M{"$last": "$score"},
+			"average_score": bson.M{"$avg": "$score"},
+			"passed":        bson.M{"$max": "$passed"},
+		}},
+	}
+
+	cursor, err := configs.GetCollection("examAttempts").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var scores ExamScores
+	if cursor.Next(ctx) {
+		err = cursor.Decode(&scores)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scores.AverageScore = math.Round(scores.AverageScore*10) / 10
+	return &scores, cursor.Err()
+}
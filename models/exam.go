@@ -3,6 +3,7 @@ package models
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -10,20 +11,28 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"prepai.app/configs"
 	"prepai.app/internal"
+	"prepai.app/prompts"
 )
 
 type Exam struct {
-	Id         bson.ObjectID           `json:"id" bson:"_id,omitempty"`
-	Title      string                  `json:"title" bson:"title,omitempty"`
-	Subject    string                  `json:"subject" bson:"subject,omitempty"`
-	Difficulty string                  `json:"difficulty" bson:"difficulty,omitempty"`
-	Type       string                  `json:"type" bson:"type,omitempty"`
-	Taken      bool                    `json:"taken" bson:"taken,omitempty"`
-	Pinned     bool                    `json:"pinned" bson:"pinned,omitempty"`
-	Passed     bool                    `json:"passed" bson:"passed,omitempty"`
-	Questions  []internal.ExamQuestion `json:"questions" bson:"questions,omitempty"`
-	UserId     bson.ObjectID           `json:"user_id" bson:"user_id"`
-	ActividyId bson.ObjectID           `json:"activity_id" bson:"activity_id"`
+	Id               bson.ObjectID           `json:"id" bson:"_id,omitempty"`
+	Title            string                  `json:"title" bson:"title,omitempty"`
+	Subject          string                  `json:"subject" bson:"subject,omitempty"`
+	Difficulty       string                  `json:"difficulty" bson:"difficulty,omitempty"`
+	Type             string                  `json:"type" bson:"type,omitempty"`
+	Taken            bool                    `json:"taken" bson:"taken,omitempty"`
+	Pinned           bool                    `json:"pinned" bson:"pinned,omitempty"`
+	Passed           bool                    `json:"passed" bson:"passed,omitempty"`
+	Questions        []internal.ExamQuestion `json:"questions" bson:"questions,omitempty"`
+	Regenerations    int64                   `json:"regenerations" bson:"regenerations,omitempty"`
+	Scores           *ExamScores             `json:"scores,omitempty" bson:"scores,omitempty"`
+	Language         string                  `json:"language" bson:"language,omitempty"`
+	TimeLimit        int64                   `json:"time_limit" bson:"time_limit,omitempty"`
+	Prompt           *prompts.Ref            `json:"prompt,omitempty" bson:"prompt,omitempty"`
+	ShuffleSeed      int64                   `json:"-" bson:"shuffle_seed,omitempty"`
+	CorrectPositions []int64                 `json:"-" bson:"correct_positions,omitempty"`
+	UserId           bson.ObjectID           `json:"user_id" bson:"user_id"`
+	ActividyId       bson.ObjectID           `json:"activity_id" bson:"activity_id"`
 }
 
 func GetExams(userId bson.ObjectID) ([]Exam, error) {
@@ -60,8 +69,11 @@ func GetExamById(examId bson.ObjectID, showAnswers bool) (*Exam, error) {
 	if !showAnswers {
 		projection = bson.M{
 			"questions": bson.M{
-				"correct":     0,
-				"explanation": 0,
+				"correct":         0,
+				"correctoptions":  0,
+				"correctorder":    0,
+				"referenceanswer": 0,
+				"explanation":     0,
 			},
 		}
 	}
@@ -80,6 +92,62 @@ func GetExamById(examId bson.ObjectID, showAnswers bool) (*Exam, error) {
 	return &exam, nil
 }
 
+func (exam Exam) QuestionIds() []string {
+	ids := make([]string, len(exam.Questions))
+	for i, question := range exam.Questions {
+		ids[i] = question.Id
+	}
+
+	return ids
+}
+
+// Exams generated before questions had ids get them the first time they are
+// attempted. Only the ids are written, the exam may have been loaded without
+// its answers.
+func (exam *Exam) AssignQuestionIds() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{}
+	for i := range exam.Questions {
+		if exam.Questions[i].Id == "" {
+			exam.Questions[i].Id = internal.NewQuestionId()
+			set[fmt.Sprintf("questions.%v.id", i)] = exam.Questions[i].Id
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	collection := configs.GetCollection("exams")
+	filter := bson.M{
+		"_id":          exam.Id,
+		"questions.id": bson.M{"$exists": false},
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 1 {
+		return nil
+	}
+
+	// Someone else gave them ids first, those are the ones that count
+	stored, err := GetExamById(exam.Id, false)
+	if err != nil {
+		return err
+	}
+	if len(stored.Questions) != len(exam.Questions) {
+		return errors.New("exam changed while assigning question ids")
+	}
+	for i := range exam.Questions {
+		exam.Questions[i].Id = stored.Questions[i].Id
+	}
+
+	return nil
+}
+
 func (exam *Exam) Save() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -99,6 +167,8 @@ func (exam *Exam) Save() error {
 	return nil
 }
 
+// Only the fields users edit are stored, the questions and their answer keys
+// change through SetQuestions.
 func (exam Exam) Update() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -106,11 +176,98 @@ func (exam Exam) Update() error {
 	collection := configs.GetCollection("exams")
 	update := bson.M{
 		"$set": bson.M{
-			"title":     exam.Title,
-			"taken":     exam.Taken,
-			"passed":    exam.Passed,
-			"pinned":    exam.Pinned,
-			"questions": exam.Questions,
+			"title":  exam.Title,
+			"pinned": exam.Pinned,
+		},
+	}
+
+	_, err := collection.UpdateByID(ctx, exam.Id, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Stores regenerated questions with the prompt they came from and how their
+// options were shuffled, counting the regeneration.
+func (exam Exam) SetQuestions() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("exams")
+	set := bson.M{
+		"title":             exam.Title,
+		"questions":         exam.Questions,
+		"shuffle_seed":      exam.ShuffleSeed,
+		"correct_positions": exam.CorrectPositions,
+	}
+	if exam.Prompt != nil {
+		set["prompt"] = exam.Prompt
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"regenerations": 1},
+	}
+
+	_, err := collection.UpdateByID(ctx, exam.Id, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// How long an attempt may take, zero when the exam is untimed. TimeLimit is
+// in seconds, a negative one makes the exam untimed and without one the
+// exam gets perQuestion for every question of its difficulty.
+func (exam Exam) AttemptDuration(perQuestion time.Duration) time.Duration {
+	if exam.TimeLimit > 0 {
+		return time.Duration(exam.TimeLimit) * time.Second
+	}
+	if exam.TimeLimit < 0 {
+		return 0
+	}
+
+	questions := internal.ExpectedExamQuestions(exam.Difficulty)
+	if questions == 0 {
+		questions = len(exam.Questions)
+	}
+
+	return time.Duration(questions) * perQuestion
+}
+
+// Stores the summary of the submitted attempts, the exam counts as passed
+// once any attempt passed.
+func (exam Exam) SetScores(scores ExamScores) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("exams")
+	update := bson.M{
+		"$set": bson.M{
+			"scores": scores,
+			"taken":  scores.Attempts > 0,
+			"passed": scores.Passed,
+		},
+	}
+
+	_, err := collection.UpdateByID(ctx, exam.Id, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (exam Exam) SetActivity(activityId bson.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("exams")
+	update := bson.M{
+		"$set": bson.M{
+			"activity_id": activityId,
 		},
 	}
 
@@ -133,5 +290,67 @@ func (exam Exam) Delete() error {
 		return err
 	}
 
+	// Its attempts cannot be graded without it
+	_, err = configs.GetCollection("examAttempts").DeleteMany(ctx, bson.M{"exam_id": exam.Id})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// Where the correct answers of every exam of a type are, summed by option
+// index. An even spread means the shuffling works.
+type PositionStats struct {
+	Type      string  `json:"type"`
+	Questions int64   `json:"questions"`
+	Positions []int64 `json:"positions"`
+}
+
+func GetCorrectPositionStats() ([]PositionStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"correct_positions": bson.M{"$exists": true}}},
+		{"$unwind": bson.M{"path": "$correct_positions", "includeArrayIndex": "position"}},
+		{"$group": bson.M{
+			"_id":   bson.M{"type": "$type", "position": "$position"},
+			"count": bson.M{"$sum": "$correct_positions"},
+		}},
+		{"$sort": bson.M{"_id.type": 1, "_id.position": 1}},
+	}
+
+	cursor, err := configs.GetCollection("exams").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var counts []struct {
+		Id struct {
+			Type     string `bson:"type"`
+			Position int64  `bson:"position"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	err = cursor.All(ctx, &counts)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := []PositionStats{}
+	for _, count := range counts {
+		if len(stats) == 0 || stats[len(stats)-1].Type != count.Id.Type {
+			stats = append(stats, PositionStats{Type: count.Id.Type})
+		}
+		typeStats := &stats[len(stats)-1]
+		for int64(len(typeStats.Positions)) <= count.Id.Position {
+			typeStats.Positions = append(typeStats.Positions, 0)
+		}
+		typeStats.Positions[count.Id.Position] = count.Count
+		typeStats.Questions += count.Count
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/configs"
+	"prepai.app/prompts"
+)
+
+type VariantResult struct {
+	Version          int     `json:"version"`
+	Weight           int     `json:"weight"`
+	Generated        int64   `json:"generated"`
+	Regenerations    int64   `json:"regenerations"`
+	RegenerationRate float64 `json:"regeneration_rate"`
+	Attempts         int64   `json:"attempts"`
+	AverageScore     float64 `json:"average_score"`
+	Flags            int64   `json:"flags"`
+	FlagRate         float64 `json:"flag_rate"`
+}
+
+type ExperimentResult struct {
+	Name     string          `json:"name"`
+	Prompt   string          `json:"prompt"`
+	Variants []VariantResult `json:"variants"`
+}
+
+// Where the documents generated by each prompt live, and the attempts that
+// score them. Feedback is scored by the attempt that holds it.
+var experimentSources = map[string]struct {
+	collection   string
+	attempts     string
+	foreignField string
+}{
+	prompts.Exam:      {collection: "exams", attempts: "examAttempts", foreignField: "exam_id"},
+	prompts.Interview: {collection: "interviews", attempts: "interviewAttempts", foreignField: "interview_id"},
+	prompts.Feedback:  {collection: "interviewAttempts"},
+}
+
+type variantTotals struct {
+	Version       int     `bson:"_id"`
+	Generated     int64   `bson:"generated"`
+	Regenerations int64   `bson:"regenerations"`
+	ScoreSum      float64 `bson:"score_sum"`
+	ScoreCount    int64   `bson:"score_count"`
+}
+
+// Aggregates the outcome of every variant of the experiment: how often its
+// documents are regenerated, the average score of their attempts and how
+// often users flag them.
+func GetExperimentResult(experiment prompts.Experiment) (*ExperimentResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	source := experimentSources[experiment.Prompt]
+	match := bson.M{"$match": bson.M{
+		"prompt.name":       experiment.Prompt,
+		"prompt.experiment": experiment.Name,
+	}}
+
+	var pipeline []bson.M
+	if source.attempts != "" {
+		// Only attempts with answers were submitted and have a score
+		submitted := bson.M{"$filter": bson.M{
+			"input": "$attempts",
+			"cond":  bson.M{"$gt": []any{bson.M{"$size": bson.M{"$ifNull": []any{"$$this.answers", []any{}}}}, 0}},
+		}}
+		pipeline = []bson.M{
+			match,
+			{"$lookup": bson.M{
+				"from":         source.attempts,
+				"localField":   "_id",
+				"foreignField": source.foreignField,
+				"as":           "attempts",
+			}},
+			{"$project": bson.M{
+				"version":       "$prompt.version",
+				"regenerations": bson.M{"$ifNull": []any{"$regenerations", 0}},
+				"score_sum":     bson.M{"$sum": bson.M{"$map": bson.M{"input": submitted, "in": "$$this.score"}}},
+				"score_count":   bson.M{"$size": submitted},
+			}},
+		}
+	} else {
+		pipeline = []bson.M{
+			match,
+			{"$project": bson.M{
+				"version":       "$prompt.version",
+				"regenerations": bson.M{"$literal": 0},
+				"score_sum":     bson.M{"$ifNull": []any{"$score", 0}},
+				"score_count":   bson.M{"$literal": 1},
+			}},
+		}
+	}
+	pipeline = append(pipeline, bson.M{"$group": bson.M{
+		"_id":           "$version",
+		"generated":     bson.M{"$sum": 1},
+		"regenerations": bson.M{"$sum": "$regenerations"},
+		"score_sum":     bson.M{"$sum": "$score_sum"},
+		"score_count":   bson.M{"$sum": "$score_count"},
+	}})
+
+	cursor, err := configs.GetCollection(source.collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []variantTotals
+	err = cursor.All(ctx, &totals)
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := countFlagsByVersion(ctx, experiment)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]variantTotals)
+	for _, total := range totals {
+		byVersion[total.Version] = total
+	}
+
+	result := ExperimentResult{
+		Name:     experiment.Name,
+		Prompt:   experiment.Prompt,
+		Variants: make([]VariantResult, len(experiment.Variants)),
+	}
+	for i, variant := range experiment.Variants {
+		total := byVersion[variant.Version]
+		variantResult := VariantResult{
+			Version:       variant.Version,
+			Weight:        variant.Weight,
+			Generated:     total.Generated,
+			Regenerations: total.Regenerations,
+			Attempts:      total.ScoreCount,
+			Flags:         flags[variant.Version],
+		}
+		if total.Generated > 0 {
+			variantResult.RegenerationRate = float64(total.Regenerations) / float64(total.Generated)
+			variantResult.FlagRate = float64(variantResult.Flags) / float64(total.Generated)
+		}
+		if total.ScoreCount > 0 {
+			variantResult.AverageScore = total.ScoreSum / float64(total.ScoreCount)
+		}
+		result.Variants[i] = variantResult
+	}
+
+	return &result, nil
+}
+
+func countFlagsByVersion(ctx context.Context, experiment prompts.Experiment) (map[int]int64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"prompt.name":       experiment.Prompt,
+			"prompt.experiment": experiment.Name,
+		}},
+		{"$group": bson.M{
+			"_id":   "$prompt.version",
+			"flags": bson.M{"$sum": 1},
+		}},
+	}
+
+	cursor, err := configs.GetCollection("flags").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var counts []struct {
+		Version int   `bson:"_id"`
+		Flags   int64 `bson:"flags"`
+	}
+	err = cursor.All(ctx, &counts)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make(map[int]int64)
+	for _, count := range counts {
+		flags[count.Version] = count.Flags
+	}
+
+	return flags, nil
+}
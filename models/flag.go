@@ -0,0 +1,51 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"prepai.app/configs"
+	"prepai.app/prompts"
+)
+
+// Feedback flags point to the interview attempt that holds the feedback.
+const ContentFeedback = "feedback"
+
+var ErrAlreadyFlagged = errors.New("content already flagged")
+
+// Flag is a user reporting a generated document as wrong or low quality. It
+// keeps the prompt of the document so experiments can count it.
+type Flag struct {
+	Id          bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	ContentType string        `json:"content_type" bson:"content_type"`
+	ContentId   bson.ObjectID `json:"content_id" bson:"content_id"`
+	Reason      string        `json:"reason" bson:"reason,omitempty"`
+	Prompt      *prompts.Ref  `json:"prompt,omitempty" bson:"prompt,omitempty"`
+	UserId      bson.ObjectID `json:"user_id" bson:"user_id"`
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+}
+
+func (flag *Flag) Save() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("flags")
+	result, err := collection.InsertOne(ctx, flag)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAlreadyFlagged
+		}
+		return err
+	}
+
+	id, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return errors.New("failed to get document id")
+	}
+
+	flag.Id = id
+	return nil
+}
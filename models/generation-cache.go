@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"prepai.app/configs"
+)
+
+// CachedGeneration is a validated model response stored under the hash of
+// the request that produced it.
+type CachedGeneration struct {
+	Key       string    `json:"key" bson:"_id"`
+	Feature   string    `json:"feature" bson:"feature"`
+	Text      string    `json:"text" bson:"text"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// GenerationCache implements internal.Cache over the generationCache
+// collection. Expired entries are ignored and removed by a TTL index.
+type GenerationCache struct{}
+
+func (GenerationCache) TTL(feature string) time.Duration {
+	return configs.GetCacheTTL(feature)
+}
+
+func (GenerationCache) Get(ctx context.Context, key string) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var cached CachedGeneration
+	err := configs.GetCollection("generationCache").FindOne(ctx, bson.M{
+		"_id":        key,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&cached)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("failed to read generation cache: %v", err)
+		}
+		return "", false
+	}
+
+	return cached.Text, true
+}
+
+func (GenerationCache) Set(ctx context.Context, key string, feature string, text string, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	cached := CachedGeneration{
+		Key:       key,
+		Feature:   feature,
+		Text:      text,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	_, err := configs.GetCollection("generationCache").ReplaceOne(ctx, bson.M{"_id": key}, cached, options.Replace().SetUpsert(true))
+	if err != nil {
+		// A missed write only costs another call to the model
+		log.Printf("failed to write generation cache: %v", err)
+	}
+}
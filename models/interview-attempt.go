@@ -8,9 +8,11 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"prepai.app/configs"
+	"prepai.app/prompts"
 )
 
 type InterviewAnswer struct {
+	QuestionId   string  `json:"question_id,omitempty" bson:"question_id,omitempty"`
 	Question     string  `json:"question" bson:"question,omitempty"`
 	UserResponse string  `json:"user_response" bson:"user_response,omitempty"`
 	Feedback     string  `json:"feedback" bson:"feedback,omitempty"`
@@ -26,6 +28,8 @@ type InterviewAttempt struct {
 	AreasToImprove []string          `json:"areas_to_improve" bson:"areas_to_improve,omitempty"`
 	Passed         bool              `json:"passed" bson:"passed,omitempty"`
 	Score          float64           `json:"score" bson:"score,omitempty"`
+	Language       string            `json:"language" bson:"language,omitempty"`
+	Prompt         *prompts.Ref      `json:"prompt,omitempty" bson:"prompt,omitempty"`
 	UserId         bson.ObjectID     `json:"user_id" bson:"user_id"`
 	InterviewId    bson.ObjectID     `json:"interview_id" bson:"interview_id"`
 }
@@ -80,6 +84,7 @@ func (attempt InterviewAttempt) Update() error {
 			"analysis":         attempt.Analysis,
 			"areas_to_improve": attempt.AreasToImprove,
 			"strengths":        attempt.Strengths,
+			"prompt":           attempt.Prompt,
 		},
 	}
 
@@ -10,20 +10,24 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"prepai.app/configs"
 	"prepai.app/internal"
+	"prepai.app/prompts"
 )
 
 type Interview struct {
-	Id         bson.ObjectID                `json:"id" bson:"_id,omitempty"`
-	Title      string                       `json:"title" bson:"title,omitempty"`
-	JobRole    string                       `json:"job_role" bson:"job_role,omitempty" validate:"required"`
-	JobLevel   string                       `json:"job_level" bson:"job_level,omitempty" validate:"required"`
-	Topics     []string                     `json:"topics" bson:"topics,omitempty" validate:"required"`
-	Taken      bool                         `json:"taken" bson:"taken,omitempty"`
-	Pinned     bool                         `json:"pinned" bson:"pinned,omitempty"`
-	Passed     bool                         `json:"passed" bson:"passed,omitempty"`
-	Questions  []internal.InterviewQuestion `json:"questions" bson:"questions,omitempty"`
-	UserId     bson.ObjectID                `json:"user_id" bson:"user_id"`
-	ActividyId bson.ObjectID                `json:"activity_id" bson:"activity_id"`
+	Id            bson.ObjectID                `json:"id" bson:"_id,omitempty"`
+	Title         string                       `json:"title" bson:"title,omitempty"`
+	JobRole       string                       `json:"job_role" bson:"job_role,omitempty" validate:"required"`
+	JobLevel      string                       `json:"job_level" bson:"job_level,omitempty" validate:"required"`
+	Topics        []string                     `json:"topics" bson:"topics,omitempty" validate:"required"`
+	Taken         bool                         `json:"taken" bson:"taken,omitempty"`
+	Pinned        bool                         `json:"pinned" bson:"pinned,omitempty"`
+	Passed        bool                         `json:"passed" bson:"passed,omitempty"`
+	Questions     []internal.InterviewQuestion `json:"questions" bson:"questions,omitempty"`
+	Regenerations int64                        `json:"regenerations" bson:"regenerations,omitempty"`
+	Language      string                       `json:"language" bson:"language,omitempty"`
+	Prompt        *prompts.Ref                 `json:"prompt,omitempty" bson:"prompt,omitempty"`
+	UserId        bson.ObjectID                `json:"user_id" bson:"user_id"`
+	ActividyId    bson.ObjectID                `json:"activity_id" bson:"activity_id"`
 }
 
 func GetAllUserInterviews(userId bson.ObjectID) ([]Interview, error) {
@@ -90,14 +94,38 @@ func (interview Interview) Update() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	collection := configs.GetCollection("interviews")
+	set := bson.M{
+		"title":         interview.Title,
+		"taken":         interview.Taken,
+		"passed":        interview.Passed,
+		"pinned":        interview.Pinned,
+		"questions":     interview.Questions,
+		"regenerations": interview.Regenerations,
+	}
+	if interview.Prompt != nil {
+		set["prompt"] = interview.Prompt
+	}
+	update := bson.M{
+		"$set": set,
+	}
+
+	_, err := collection.UpdateByID(ctx, interview.Id, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (interview Interview) SetActivity(activityId bson.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
 	collection := configs.GetCollection("interviews")
 	update := bson.M{
 		"$set": bson.M{
-			"title":     interview.Title,
-			"taken":     interview.Taken,
-			"passed":    interview.Passed,
-			"pinned":    interview.Pinned,
-			"questions": interview.Questions,
+			"activity_id": activityId,
 		},
 	}
 
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"prepai.app/configs"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+const (
+	JobCreateExam          = "create_exam"
+	JobRegenerateExam      = "regenerate_exam"
+	JobCreateInterview     = "create_interview"
+	JobRegenerateInterview = "regenerate_interview"
+	JobCreateQuestion      = "create_question"
+	JobCreateResume        = "create_resume"
+	JobTranslateExam       = "translate_exam"
+	JobTranslateInterview  = "translate_interview"
+)
+
+type Job struct {
+	Id          bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type        string        `json:"type" bson:"type"`
+	Status      string        `json:"status" bson:"status"`
+	Payload     bson.Raw      `json:"-" bson:"payload,omitempty"`
+	Attempts    int64         `json:"attempts" bson:"attempts"`
+	Error       string        `json:"error,omitempty" bson:"error,omitempty"`
+	ResultType  string        `json:"result_type,omitempty" bson:"result_type,omitempty"`
+	ResultId    bson.ObjectID `json:"result_id,omitempty" bson:"result_id,omitempty"`
+	UserId      bson.ObjectID `json:"user_id" bson:"user_id"`
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+	StartedAt   time.Time     `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	CompletedAt time.Time     `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+func NewJob(jobType string, userId bson.ObjectID, payload any) (*Job, error) {
+	raw, err := bson.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Job{
+		Type:      jobType,
+		Status:    JobQueued,
+		Payload:   raw,
+		UserId:    userId,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func GetJobById(jobId bson.ObjectID) (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("jobs")
+	opts := options.FindOne().SetProjection(bson.M{"payload": 0})
+
+	var job Job
+	err := collection.FindOne(ctx, bson.M{"_id": jobId}, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Takes the oldest queued job, or a running one whose worker stopped
+// answering, and marks it as running. Returns nil when there is nothing to do.
+func ClaimNextJob(staleAfter time.Duration, maxAttempts int64) (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("jobs")
+	now := time.Now()
+
+	filter := bson.M{
+		"attempts": bson.M{"$lt": maxAttempts},
+		"$or": []bson.M{
+			{"status": JobQueued},
+			{"status": JobRunning, "started_at": bson.M{"$lt": now.Add(-staleAfter)}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     JobRunning,
+			"started_at": now,
+		},
+		"$inc": bson.M{
+			"attempts": 1,
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job Job
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Jobs that ran out of attempts while running are left as failed.
+func FailExhaustedJobs(staleAfter time.Duration, maxAttempts int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("jobs")
+	filter := bson.M{
+		"status":     JobRunning,
+		"attempts":   bson.M{"$gte": maxAttempts},
+		"started_at": bson.M{"$lt": time.Now().Add(-staleAfter)},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       JobFailed,
+			"error":        "job stopped responding",
+			"completed_at": time.Now(),
+		},
+	}
+
+	_, err := collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+func (job *Job) Save() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("jobs")
+	result, err := collection.InsertOne(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	id, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return errors.New("failed to get document id")
+	}
+
+	job.Id = id
+	return nil
+}
+
+func (job *Job) Complete(resultType string, resultId bson.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job.Status = JobCompleted
+	job.ResultType = resultType
+	job.ResultId = resultId
+	job.CompletedAt = time.Now()
+
+	collection := configs.GetCollection("jobs")
+	update := bson.M{
+		"$set": bson.M{
+			"status":       job.Status,
+			"result_type":  job.ResultType,
+			"result_id":    job.ResultId,
+			"completed_at": job.CompletedAt,
+		},
+		"$unset": bson.M{
+			"payload": "",
+		},
+	}
+
+	_, err := collection.UpdateByID(ctx, job.Id, update)
+	return err
+}
+
+func (job *Job) Fail(reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job.Status = JobFailed
+	job.Error = reason
+	job.CompletedAt = time.Now()
+
+	collection := configs.GetCollection("jobs")
+	update := bson.M{
+		"$set": bson.M{
+			"status":       job.Status,
+			"error":        job.Error,
+			"completed_at": job.CompletedAt,
+		},
+		"$unset": bson.M{
+			"payload": "",
+		},
+	}
+
+	_, err := collection.UpdateByID(ctx, job.Id, update)
+	return err
+}
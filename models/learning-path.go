@@ -0,0 +1,188 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"prepai.app/configs"
+)
+
+type PathStep struct {
+	Title      string        `json:"title" bson:"title,omitempty"`
+	Type       string        `json:"type" bson:"type,omitempty"`
+	Order      int64         `json:"order" bson:"order"`
+	Difficulty string        `json:"difficulty" bson:"difficulty,omitempty"`
+	ActivityId bson.ObjectID `json:"activity_id" bson:"activity_id,omitempty"`
+}
+
+type PathModule struct {
+	Title     string     `json:"title" bson:"title,omitempty"`
+	Objective string     `json:"objective" bson:"objective,omitempty"`
+	Order     int64      `json:"order" bson:"order"`
+	Topic     string     `json:"topic" bson:"topic,omitempty"`
+	Steps     []PathStep `json:"steps" bson:"steps,omitempty"`
+}
+
+type LearningPath struct {
+	Id             bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	Title          string        `json:"title" bson:"title,omitempty"`
+	JobRole        string        `json:"job_role" bson:"job_role,omitempty" validate:"required"`
+	JobLevel       string        `json:"job_level" bson:"job_level,omitempty" validate:"required"`
+	JobDescription string        `json:"job_description" bson:"job_description,omitempty"`
+	Topics         []string      `json:"topics" bson:"topics,omitempty" validate:"required"`
+	Pinned         bool          `json:"pinned" bson:"pinned,omitempty"`
+	Percent        float64       `json:"percent" bson:"percent"`
+	Completed      bool          `json:"completed" bson:"completed,omitempty"`
+	Modules        []PathModule  `json:"modules" bson:"modules,omitempty"`
+	Language       string        `json:"language" bson:"language,omitempty"`
+	UserId         bson.ObjectID `json:"user_id" bson:"user_id"`
+}
+
+func GetAllUserLearningPaths(userId bson.ObjectID) ([]LearningPath, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("learningPaths")
+	projection := bson.M{
+		"modules": 0,
+	}
+	opts := options.Find().SetProjection(projection)
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userId}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []LearningPath
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func GetLearningPathById(pathId bson.ObjectID) (*LearningPath, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("learningPaths")
+
+	var path LearningPath
+	err := collection.FindOne(ctx, bson.M{"_id": pathId}).Decode(&path)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	return &path, nil
+}
+
+func (path *LearningPath) Save() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("learningPaths")
+	result, err := collection.InsertOne(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	id, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return errors.New("failed to get document id")
+	}
+
+	path.Id = id
+	return nil
+}
+
+// Modules are left out, they only change through SetModuleSteps so steps
+// stored by another request are never overwritten.
+func (path LearningPath) Update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("learningPaths")
+	update := bson.M{
+		"$set": bson.M{
+			"title":     path.Title,
+			"pinned":    path.Pinned,
+			"percent":   path.Percent,
+			"completed": path.Completed,
+		},
+	}
+
+	_, err := collection.UpdateByID(ctx, path.Id, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Stores the generated steps of a module. It only succeeds when no other
+// request stored steps first, returning false when the module was taken.
+func (path *LearningPath) SetModuleSteps(order int64, steps []PathStep) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("learningPaths")
+	filter := bson.M{
+		"_id": path.Id,
+		"modules": bson.M{
+			"$elemMatch": bson.M{
+				"order":   order,
+				"steps.0": bson.M{"$exists": false},
+			},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"modules.$.steps": steps,
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	if module := path.GetModule(order); module != nil {
+		module.Steps = steps
+	}
+	return true, nil
+}
+
+func (path LearningPath) Delete() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("learningPaths")
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": path.Id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Returns the module with the given order, or nil when the path has none.
+func (path *LearningPath) GetModule(order int64) *PathModule {
+	for i := range path.Modules {
+		if path.Modules[i].Order == order {
+			return &path.Modules[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"prepai.app/configs"
+	"prepai.app/internal"
+)
+
+type Lesson struct {
+	Id           bson.ObjectID            `json:"id" bson:"_id,omitempty"`
+	Title        string                   `json:"title" bson:"title,omitempty"`
+	Difficulty   string                   `json:"difficulty" bson:"difficulty,omitempty"`
+	Summary      string                   `json:"summary" bson:"summary,omitempty"`
+	Sections     []internal.LessonSection `json:"sections" bson:"sections,omitempty"`
+	KeyTakeaways []string                 `json:"key_takeaways" bson:"key_takeaways,omitempty"`
+	Language     string                   `json:"language" bson:"language,omitempty"`
+	UserId       bson.ObjectID            `json:"user_id" bson:"user_id"`
+	ActivityId   bson.ObjectID            `json:"activity_id" bson:"activity_id"`
+}
+
+func GetLessonById(lessonId bson.ObjectID) (*Lesson, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("lessons")
+
+	var lesson Lesson
+	err := collection.FindOne(ctx, bson.M{"_id": lessonId}).Decode(&lesson)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	return &lesson, nil
+}
+
+func (lesson *Lesson) Save() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("lessons")
+	result, err := collection.InsertOne(ctx, lesson)
+	if err != nil {
+		return err
+	}
+
+	id, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return errors.New("failed to get document id")
+	}
+
+	lesson.Id = id
+	return nil
+}
+
+func (lesson Lesson) Delete() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("lessons")
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": lesson.Id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type ModuleProgress struct {
+	Order          int64   `json:"order"`
+	Title          string  `json:"title"`
+	TotalSteps     int     `json:"total_steps"`
+	CompletedSteps int     `json:"completed_steps"`
+	Percent        float64 `json:"percent"`
+	Unlocked       bool    `json:"unlocked"`
+	Completed      bool    `json:"completed"`
+}
+
+type PathProgress struct {
+	PathId    bson.ObjectID    `json:"path_id"`
+	Modules   []ModuleProgress `json:"modules"`
+	Percent   float64          `json:"percent"`
+	Completed bool             `json:"completed"`
+}
+
+// The final challenge closes the path, it is the module with the highest
+// order. Its title is written in the path language, so it is not looked up
+// by name.
+func (path LearningPath) FinalModuleOrder() int64 {
+	final := int64(math.MinInt64)
+	for _, module := range path.Modules {
+		if module.Order > final {
+			final = module.Order
+		}
+	}
+
+	return final
+}
+
+func (path LearningPath) sortedModules() []PathModule {
+	modules := make([]PathModule, len(path.Modules))
+	copy(modules, path.Modules)
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Order < modules[j].Order
+	})
+
+	return modules
+}
+
+// Builds the progress of every module using the path activities. A module is
+// unlocked when the previous one is completed, except for the final challenge
+// which needs every other module completed first.
+func (path LearningPath) Progress(activities []Activity) PathProgress {
+	byModule := make(map[int64][]Activity)
+	for _, activity := range activities {
+		byModule[activity.ModuleOrder] = append(byModule[activity.ModuleOrder], activity)
+	}
+
+	finalOrder := path.FinalModuleOrder()
+	modules := path.sortedModules()
+	progress := PathProgress{
+		PathId:  path.Id,
+		Modules: make([]ModuleProgress, len(modules)),
+	}
+
+	for i, module := range modules {
+		moduleActivities := byModule[module.Order]
+
+		completed := 0
+		for _, activity := range moduleActivities {
+			if activity.Status == ActivityCompleted {
+				completed++
+			}
+		}
+
+		percent := 0.0
+		if len(moduleActivities) > 0 {
+			percent = math.Round(float64(completed)/float64(len(moduleActivities))*1000) / 10
+		}
+
+		progress.Modules[i] = ModuleProgress{
+			Order:          module.Order,
+			Title:          module.Title,
+			TotalSteps:     len(moduleActivities),
+			CompletedSteps: completed,
+			Percent:        percent,
+			Completed:      len(moduleActivities) > 0 && completed == len(moduleActivities),
+		}
+	}
+
+	othersCompleted := true
+	for i, module := range progress.Modules {
+		if module.Order == finalOrder {
+			continue
+		}
+
+		progress.Modules[i].Unlocked = i == 0 || progress.Modules[i-1].Completed
+		if !module.Completed {
+			othersCompleted = false
+		}
+	}
+
+	totalPercent := 0.0
+	for i, module := range progress.Modules {
+		if module.Order == finalOrder {
+			progress.Modules[i].Unlocked = othersCompleted || len(progress.Modules) == 1
+			progress.Completed = module.Completed
+		}
+		totalPercent += module.Percent
+	}
+
+	if len(progress.Modules) > 0 {
+		progress.Percent = math.Round(totalPercent/float64(len(progress.Modules))*10) / 10
+	}
+
+	// Without the final challenge the path is never fully done
+	if !progress.Completed && progress.Percent >= 100 {
+		progress.Percent = 99.9
+	}
+
+	return progress
+}
+
+func (progress PathProgress) GetModule(order int64) *ModuleProgress {
+	for i := range progress.Modules {
+		if progress.Modules[i].Order == order {
+			return &progress.Modules[i]
+		}
+	}
+
+	return nil
+}
+
+// Marks the activity as completed and unlocks whatever comes next in its
+// learning path: the following step of the module or, once the module is
+// done, the first step of the modules that became available.
+func (activity *Activity) Complete() error {
+	if activity.Status != ActivityCompleted {
+		activity.Status = ActivityCompleted
+		err := activity.Update()
+		if err != nil {
+			return err
+		}
+	}
+
+	if activity.PathId.IsZero() {
+		return nil
+	}
+
+	path, err := GetLearningPathById(activity.PathId)
+	if err != nil {
+		return err
+	}
+
+	activities, err := GetActivitiesByPathId(path.Id)
+	if err != nil {
+		return err
+	}
+
+	progress, unlocked := path.Advance(activities, activity.Id)
+	for _, next := range unlocked {
+		err = next.Update()
+		if err != nil {
+			return err
+		}
+	}
+
+	path.Percent = progress.Percent
+	path.Completed = progress.Completed
+
+	return path.Update()
+}
+
+// Marks the activity as completed among the path activities and makes the
+// steps that come next available. Returns the resulting progress and the
+// activities that were unlocked.
+func (path LearningPath) Advance(activities []Activity, completedId bson.ObjectID) (PathProgress, []*Activity) {
+	for i := range activities {
+		if activities[i].Id == completedId {
+			activities[i].Status = ActivityCompleted
+		}
+	}
+
+	progress := path.Progress(activities)
+
+	var unlocked []*Activity
+	for _, module := range progress.Modules {
+		if !module.Unlocked || module.Completed {
+			continue
+		}
+
+		next := nextLockedActivity(activities, module.Order)
+		if next == nil {
+			continue
+		}
+
+		next.Status = ActivityAvailable
+		unlocked = append(unlocked, next)
+	}
+
+	return progress, unlocked
+}
+
+// Returns the first locked step of a module as long as every step before it
+// has been completed.
+func nextLockedActivity(activities []Activity, moduleOrder int64) *Activity {
+	var next *Activity
+	for i := range activities {
+		activity := &activities[i]
+		if activity.ModuleOrder != moduleOrder || activity.Status == ActivityCompleted {
+			continue
+		}
+		if next == nil || activity.StepOrder < next.StepOrder {
+			next = activity
+		}
+	}
+
+	if next == nil || next.Status != ActivityLocked {
+		return nil
+	}
+
+	return next
+}
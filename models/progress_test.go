@@ -0,0 +1,120 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// A path whose modules mix every kind of step, as generated: only the first
+// step of the first module is available.
+func mixedPath() (LearningPath, []Activity) {
+	path := LearningPath{
+		Id: bson.NewObjectID(),
+		Modules: []PathModule{
+			{Title: "Goroutines", Order: 1},
+			{Title: "Channels", Order: 2},
+			{Title: "Desafío final", Order: 3},
+		},
+	}
+
+	stepTypes := []string{"lesson", "mock exam", "open question", "mock interview"}
+
+	var activities []Activity
+	for _, module := range path.Modules {
+		for i, stepType := range stepTypes {
+			activities = append(activities, Activity{
+				Id:          bson.NewObjectID(),
+				ContentType: ContentTypeFromStep(stepType),
+				Status:      ActivityLocked,
+				PathId:      path.Id,
+				ModuleOrder: module.Order,
+				StepOrder:   int64(i + 1),
+			})
+		}
+	}
+	activities[0].Status = ActivityAvailable
+
+	return path, activities
+}
+
+func TestPathCompletesWithMixedSteps(t *testing.T) {
+	path, activities := mixedPath()
+
+	completedBy := make(map[string]int)
+	var progress PathProgress
+	for range activities {
+		var next *Activity
+		for i := range activities {
+			if activities[i].Status == ActivityAvailable && (next == nil || activities[i].ModuleOrder < next.ModuleOrder) {
+				next = &activities[i]
+			}
+		}
+		if next == nil {
+			t.Fatalf("path stalled at %v%% with no available step", progress.Percent)
+		}
+
+		// Lessons and questions are completed by the user, the rest when
+		// their attempt is submitted
+		if next.CompletedByUser() {
+			completedBy["user"]++
+		} else {
+			completedBy["submission"]++
+		}
+
+		final := progress.GetModule(3)
+		if next.ModuleOrder == 3 && (final == nil || !final.Unlocked) {
+			t.Fatal("final module step available before the module was unlocked")
+		}
+
+		progress, _ = path.Advance(activities, next.Id)
+	}
+
+	if !progress.Completed || progress.Percent != 100 {
+		t.Fatalf("path ended at %v%%, completed %v", progress.Percent, progress.Completed)
+	}
+	if completedBy["user"] != 6 || completedBy["submission"] != 6 {
+		t.Fatalf("completed %v", completedBy)
+	}
+	for _, activity := range activities {
+		if activity.Status != ActivityCompleted {
+			t.Fatalf("step %v of module %v was left %v", activity.StepOrder, activity.ModuleOrder, activity.Status)
+		}
+	}
+}
+
+func TestAdvanceUnlocksNextStep(t *testing.T) {
+	path, activities := mixedPath()
+
+	progress, unlocked := path.Advance(activities, activities[0].Id)
+	if len(unlocked) != 1 || unlocked[0].Id != activities[1].Id || activities[1].Status != ActivityAvailable {
+		t.Fatalf("unlocked %+v, want the second step", unlocked)
+	}
+	if progress.Percent != 8.3 || progress.Completed {
+		t.Fatalf("got %+v", progress)
+	}
+
+	// Completing a step again unlocks nothing new
+	_, unlocked = path.Advance(activities, activities[0].Id)
+	if len(unlocked) != 0 {
+		t.Fatalf("unlocked %+v again", unlocked)
+	}
+}
+
+func TestCompletedByUser(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{ContentLesson, true},
+		{ContentQuestion, true},
+		{ContentExam, false},
+		{ContentInterview, false},
+	}
+
+	for _, test := range tests {
+		if got := (Activity{ContentType: test.contentType}).CompletedByUser(); got != test.want {
+			t.Errorf("%v: CompletedByUser() = %v, want %v", test.contentType, got, test.want)
+		}
+	}
+}
@@ -20,6 +20,7 @@ type Question struct {
 	Explanation    string                  `json:"explanation" bson:"explanation,omitempty"`
 	ExpectedLength string                  `json:"expected_length" bson:"expected_length,omitempty"`
 	IdealAnswer    internal.QuestionAnswer `json:"ideal_answer" bson:"ideal_answer,omitempty"`
+	Language       string                  `json:"language" bson:"language,omitempty"`
 	UserId         bson.ObjectID           `json:"user_id" bson:"user_id"`
 	Pinned         bool                    `json:"pinned" bson:"pinned,omitempty"`
 }
@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"prepai.app/configs"
 	"prepai.app/internal"
+	"prepai.app/prompts"
 )
 
 type Resume struct {
@@ -20,6 +21,8 @@ type Resume struct {
 	AnalysisSummary        string           `json:"analysis_summary" bson:"analysis_summary,omitempty"`
 	ImprovementSuggestions string           `json:"improvement_suggestions" bson:"improvement_suggestions,omitempty"`
 	Metrics                internal.Metrics `json:"metrics" bson:"metrics,omitempty"`
+	Language               string           `json:"language" bson:"language,omitempty"`
+	Prompt                 *prompts.Ref     `json:"prompt,omitempty" bson:"prompt,omitempty"`
 	UserId                 bson.ObjectID    `json:"user_id" bson:"user_id"`
 }
 
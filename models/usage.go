@@ -0,0 +1,266 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"prepai.app/configs"
+	"prepai.app/internal"
+	"prepai.app/providers"
+)
+
+// Usage is one call to the model made on behalf of a user.
+type Usage struct {
+	Id               bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	Model            string        `json:"model" bson:"model"`
+	Feature          string        `json:"feature" bson:"feature"`
+	PromptTokens     int64         `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int64         `json:"completion_tokens" bson:"completion_tokens"`
+	UserId           bson.ObjectID `json:"user_id" bson:"user_id"`
+	CreatedAt        time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// Consumption of one quota period. A zero limit means there is none.
+type QuotaUsage struct {
+	Used     int64     `json:"used"`
+	Limit    int64     `json:"limit"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+type FeatureUsage struct {
+	Feature          string `json:"feature" bson:"feature"`
+	Model            string `json:"model" bson:"model"`
+	Calls            int64  `json:"calls" bson:"calls"`
+	PromptTokens     int64  `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens" bson:"completion_tokens"`
+}
+
+type UserUsage struct {
+	Daily    QuotaUsage     `json:"daily"`
+	Monthly  QuotaUsage     `json:"monthly"`
+	Features []FeatureUsage `json:"features"`
+}
+
+// QuotaError is returned when a user already spent the tokens of a period.
+type QuotaError struct {
+	Period   string
+	Limit    int64
+	ResetsAt time.Time
+}
+
+func (err *QuotaError) Error() string {
+	return fmt.Sprintf("%v token quota of %v exceeded, it resets at %v", err.Period, err.Limit, err.ResetsAt.Format(time.RFC3339))
+}
+
+func startOfDay(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func startOfMonth(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (usage *Usage) Save() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("usage")
+	result, err := collection.InsertOne(ctx, usage)
+	if err != nil {
+		return err
+	}
+
+	id, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return errors.New("failed to get document id")
+	}
+
+	usage.Id = id
+	return nil
+}
+
+// Sum of prompt and completion tokens spent by the user since the given time.
+func GetUserTokens(userId bson.ObjectID, since time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("usage")
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userId, "created_at": bson.M{"$gte": since}}},
+		{"$group": bson.M{
+			"_id":    nil,
+			"tokens": bson.M{"$sum": bson.M{"$add": []string{"$prompt_tokens", "$completion_tokens"}}},
+		}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Tokens int64 `bson:"tokens"`
+	}
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].Tokens, nil
+}
+
+func GetUserUsageByFeature(userId bson.ObjectID, since time.Time) ([]FeatureUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := configs.GetCollection("usage")
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userId, "created_at": bson.M{"$gte": since}}},
+		{"$group": bson.M{
+			"_id":               bson.M{"feature": "$feature", "model": "$model"},
+			"calls":             bson.M{"$sum": 1},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+		}},
+		{"$project": bson.M{
+			"_id":               0,
+			"feature":           "$_id.feature",
+			"model":             "$_id.model",
+			"calls":             1,
+			"prompt_tokens":     1,
+			"completion_tokens": 1,
+		}},
+		{"$sort": bson.D{{Key: "feature", Value: 1}, {Key: "model", Value: 1}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	features := []FeatureUsage{}
+	err = cursor.All(ctx, &features)
+	if err != nil {
+		return nil, err
+	}
+
+	return features, nil
+}
+
+// Daily and monthly consumption against the configured limits, with the
+// breakdown per feature and model for the current month.
+func GetUserUsage(userId bson.ObjectID) (*UserUsage, error) {
+	now := time.Now()
+	quota := configs.GetQuotaConfig()
+	day := startOfDay(now)
+	month := startOfMonth(now)
+
+	daily, err := GetUserTokens(userId, day)
+	if err != nil {
+		return nil, err
+	}
+
+	monthly, err := GetUserTokens(userId, month)
+	if err != nil {
+		return nil, err
+	}
+
+	features, err := GetUserUsageByFeature(userId, month)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserUsage{
+		Daily: QuotaUsage{
+			Used:     daily,
+			Limit:    quota.DailyTokens,
+			ResetsAt: day.AddDate(0, 0, 1),
+		},
+		Monthly: QuotaUsage{
+			Used:     monthly,
+			Limit:    quota.MonthlyTokens,
+			ResetsAt: month.AddDate(0, 1, 0),
+		},
+		Features: features,
+	}, nil
+}
+
+// Returns a QuotaError when the user has no tokens left today or this month.
+func CheckQuota(userId bson.ObjectID) error {
+	quota := configs.GetQuotaConfig()
+	if quota.DailyTokens == 0 && quota.MonthlyTokens == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	if quota.DailyTokens > 0 {
+		day := startOfDay(now)
+		used, err := GetUserTokens(userId, day)
+		if err != nil {
+			return err
+		}
+		if used >= quota.DailyTokens {
+			return &QuotaError{Period: "daily", Limit: quota.DailyTokens, ResetsAt: day.AddDate(0, 0, 1)}
+		}
+	}
+
+	if quota.MonthlyTokens > 0 {
+		month := startOfMonth(now)
+		used, err := GetUserTokens(userId, month)
+		if err != nil {
+			return err
+		}
+		if used >= quota.MonthlyTokens {
+			return &QuotaError{Period: "monthly", Limit: quota.MonthlyTokens, ResetsAt: month.AddDate(0, 1, 0)}
+		}
+	}
+
+	return nil
+}
+
+// UsageTracker bills generations to the user stored in the context with
+// internal.WithUserId. Calls without a user are neither limited nor recorded.
+type UsageTracker struct{}
+
+func (UsageTracker) Allow(ctx context.Context) error {
+	userId, ok := internal.UserIdFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	return CheckQuota(userId)
+}
+
+func (UsageTracker) Record(ctx context.Context, feature string, response providers.Response) {
+	userId, ok := internal.UserIdFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	usage := Usage{
+		Model:            response.Model,
+		Feature:          feature,
+		PromptTokens:     int64(response.PromptTokens),
+		CompletionTokens: int64(response.CompletionTokens),
+		UserId:           userId,
+		CreatedAt:        time.Now(),
+	}
+
+	// Losing a usage record must not fail the generation itself
+	err := usage.Save()
+	if err != nil {
+		log.Printf("failed to record usage of user %v: %v", userId.Hex(), err)
+	}
+}
@@ -20,6 +20,7 @@ type User struct {
 	Email    string        `json:"email" bson:"email" validate:"required,email"`
 	Password string        `json:"password" bson:"password" validate:"required"`
 	ImageUrl string        `json:"image_url" bson:"image_url,omitempty"`
+	Language string        `json:"language" bson:"language,omitempty"`
 }
 
 func GetUser(userId bson.ObjectID) (*User, error) {
@@ -136,11 +137,16 @@ func (user User) Update() error {
 	defer cancel()
 
 	collection := configs.GetCollection("users")
+	set := bson.M{
+		"full_name": user.FullName,
+		"image_url": user.ImageUrl,
+	}
+	// Clients that do not know about the preference keep the stored one
+	if user.Language != "" {
+		set["language"] = user.Language
+	}
 	update := bson.M{
-		"$set": bson.M{
-			"full_name": user.FullName,
-			"image_url": user.ImageUrl,
-		},
+		"$set": set,
 	}
 
 	_, err := collection.UpdateByID(ctx, user.Id, update)
@@ -0,0 +1,136 @@
+package prompts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+)
+
+// Experiments are read from this file in the override directory.
+const experimentsFile = "experiments.json"
+
+// Experiments can only target prompts whose outcome we measure. Chunked
+// exams record the Ref of their chunks, so exams too large for one call are
+// experimented on through ExamChunk.
+var experimentPrompts = map[string]bool{
+	Exam:      true,
+	ExamChunk: true,
+	Interview: true,
+	Feedback:  true,
+}
+
+// Variant is one version of the prompt and its share of the users.
+type Variant struct {
+	Version int `json:"version"`
+	Weight  int `json:"weight"`
+}
+
+// Experiment splits the users of a prompt between several of its versions.
+type Experiment struct {
+	Name     string    `json:"name"`
+	Prompt   string    `json:"prompt"`
+	Variants []Variant `json:"variants"`
+}
+
+// Assign picks the variant of a subject (usually the user id). The same
+// subject always gets the same variant while the experiment is unchanged.
+func (experiment Experiment) Assign(subject string) Variant {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(experiment.Name + ":" + subject))
+	point := int(hash.Sum32() % uint32(total))
+
+	for _, variant := range experiment.Variants {
+		if point < variant.Weight {
+			return variant
+		}
+		point -= variant.Weight
+	}
+
+	return experiment.Variants[len(experiment.Variants)-1]
+}
+
+func (registry *Registry) loadExperiments(files fs.FS, dir string) error {
+	data, err := fs.ReadFile(files, path.Join(dir, experimentsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read experiments: %v", err)
+	}
+
+	var experiments []Experiment
+	err = json.Unmarshal(data, &experiments)
+	if err != nil {
+		return fmt.Errorf("failed to parse experiments: %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, experiment := range experiments {
+		if experiment.Name == "" || names[experiment.Name] {
+			return fmt.Errorf("experiment %q: names must be unique and not empty", experiment.Name)
+		}
+		names[experiment.Name] = true
+
+		if !experimentPrompts[experiment.Prompt] {
+			return fmt.Errorf("experiment %v: prompt %q cannot be experimented on", experiment.Name, experiment.Prompt)
+		}
+		if _, ok := registry.experiments[experiment.Prompt]; ok {
+			return fmt.Errorf("experiment %v: prompt %v already has an experiment", experiment.Name, experiment.Prompt)
+		}
+		if len(experiment.Variants) < 2 {
+			return fmt.Errorf("experiment %v: needs at least 2 variants", experiment.Name)
+		}
+
+		for _, variant := range experiment.Variants {
+			if variant.Weight <= 0 {
+				return fmt.Errorf("experiment %v: variant weights must be positive", experiment.Name)
+			}
+			if _, ok := registry.templates[experiment.Prompt][variant.Version]; !ok {
+				return fmt.Errorf("experiment %v: prompt %v has no version %v", experiment.Name, experiment.Prompt, variant.Version)
+			}
+		}
+
+		registry.experiments[experiment.Prompt] = experiment
+	}
+
+	return nil
+}
+
+// Experiments returns the running experiments sorted by prompt.
+func (registry *Registry) Experiments() []Experiment {
+	experiments := make([]Experiment, 0, len(registry.experiments))
+	for _, name := range registry.Names() {
+		if experiment, ok := registry.experiments[name]; ok {
+			experiments = append(experiments, experiment)
+		}
+	}
+
+	return experiments
+}
+
+// RenderFor renders the prompt for a subject. When the prompt has an
+// experiment the subject's variant is used, even over a pinned version, and
+// the experiment is recorded in the Ref. Without a subject it is the same as
+// Render.
+func (registry *Registry) RenderFor(name string, subject string, input any) (Prompt, error) {
+	experiment, ok := registry.experiments[name]
+	if !ok || subject == "" {
+		return registry.Render(name, input)
+	}
+
+	prompt, err := registry.RenderVersion(name, experiment.Assign(subject).Version, input)
+	if err != nil {
+		return Prompt{}, err
+	}
+
+	prompt.Experiment = experiment.Name
+	return prompt, nil
+}
@@ -0,0 +1,141 @@
+package prompts
+
+import "reflect"
+
+const (
+	System = "system"
+	Exam   = "exam"
+	// Large exams are outlined into areas, then each area is generated apart
+	ExamOutline = "exam_outline"
+	ExamChunk   = "exam_chunk"
+	Interview   = "interview"
+	Feedback    = "feedback"
+	Resume      = "resume"
+	Question    = "question"
+	Modules     = "modules"
+	Steps       = "steps"
+	Lesson      = "lesson"
+	Repair      = "repair"
+	// Existing content is translated keeping everything but the text
+	TranslateExam      = "translate_exam"
+	TranslateInterview = "translate_interview"
+	// Short answers of exam attempts are graded by the model
+	GradeAnswers = "grade_answers"
+)
+
+// Language is the name of the language, e.g. "Spanish".
+type SystemInput struct {
+	Language string
+}
+
+type ExamInput struct {
+	Subject    string
+	Difficulty string
+	Type       string
+}
+
+type ExamOutlineInput struct {
+	Subject    string
+	Difficulty string
+	Areas      int
+}
+
+// Area is empty when the questions can cover the whole subject. Avoid lists
+// questions the exam already has.
+type ExamChunkInput struct {
+	Subject    string
+	Difficulty string
+	Type       string
+	Area       string
+	Count      int
+	Avoid      []string
+}
+
+type InterviewInput struct {
+	JobRole  string
+	JobLevel string
+	Topics   []string
+}
+
+type Answer struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+type FeedbackInput struct {
+	Responses []Answer
+}
+
+type ResumeInput struct {
+	JobDescription string
+}
+
+type QuestionInput struct {
+	Question string
+}
+
+type ModulesInput struct {
+	JobRole        string
+	JobLevel       string
+	JobDescription string
+	Topics         []string
+}
+
+type StepsInput struct {
+	ModuleTitle       string
+	ModuleDescription string
+	Topics            []string
+}
+
+type LessonInput struct {
+	Title      string
+	Difficulty string
+	Topics     []string
+}
+
+// Exam and Interview hold the JSON of the content to translate.
+type TranslateExamInput struct {
+	Language string
+	Type     string
+	Exam     string
+}
+
+type TranslateInterviewInput struct {
+	Language  string
+	Interview string
+}
+
+type ShortAnswer struct {
+	Question        string `json:"question"`
+	ReferenceAnswer string `json:"reference_answer"`
+	Answer          string `json:"answer"`
+}
+
+type GradeAnswersInput struct {
+	Answers []ShortAnswer
+}
+
+type RepairInput struct {
+	Violations []string
+	Previous   string
+}
+
+// The input type each prompt is rendered with.
+var inputs = map[string]reflect.Type{
+	System:      reflect.TypeOf(SystemInput{}),
+	Exam:        reflect.TypeOf(ExamInput{}),
+	ExamOutline: reflect.TypeOf(ExamOutlineInput{}),
+	ExamChunk:   reflect.TypeOf(ExamChunkInput{}),
+	Interview:   reflect.TypeOf(InterviewInput{}),
+	Feedback:    reflect.TypeOf(FeedbackInput{}),
+	Resume:      reflect.TypeOf(ResumeInput{}),
+	Question:    reflect.TypeOf(QuestionInput{}),
+	Modules:     reflect.TypeOf(ModulesInput{}),
+	Steps:       reflect.TypeOf(StepsInput{}),
+	Lesson:      reflect.TypeOf(LessonInput{}),
+	Repair:      reflect.TypeOf(RepairInput{}),
+
+	TranslateExam:      reflect.TypeOf(TranslateExamInput{}),
+	TranslateInterview: reflect.TypeOf(TranslateInterviewInput{}),
+	GradeAnswers:       reflect.TypeOf(GradeAnswersInput{}),
+}
@@ -0,0 +1,244 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"prepai.app/configs"
+)
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+// Template files are named <name>.v<version>.tmpl, e.g. exam.v2.tmpl.
+var fileName = regexp.MustCompile(`^([a-z_]+)\.v([0-9]+)\.tmpl$`)
+
+// Ref identifies the exact prompt that produced a document, and the
+// experiment that chose its version if any.
+type Ref struct {
+	Name       string `json:"name" bson:"name"`
+	Version    int    `json:"version" bson:"version"`
+	Experiment string `json:"experiment,omitempty" bson:"experiment,omitempty"`
+}
+
+// Prompt is a rendered template ready to be sent to the model.
+type Prompt struct {
+	Ref
+	Text string
+}
+
+// Registry holds every version of every prompt template. Unless a version is
+// pinned, the highest one is used.
+type Registry struct {
+	templates   map[string]map[int]*template.Template
+	pinned      map[string]int
+	experiments map[string]Experiment
+}
+
+var functions = template.FuncMap{
+	"join": strings.Join,
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// Embedded returns a registry with the templates compiled into the binary.
+func Embedded() *Registry {
+	registry := newRegistry()
+
+	err := registry.loadFS(embedded, "templates")
+	if err != nil {
+		panic(err)
+	}
+
+	return registry
+}
+
+// Load reads the embedded templates and then the ones in overrideDir, which
+// replace embedded templates with the same name and version or add new ones.
+// Experiments are read from experiments.json in the same directory. An empty
+// overrideDir only loads the embedded templates.
+func Load(overrideDir string) (*Registry, error) {
+	registry := Embedded()
+	if overrideDir == "" {
+		return registry, nil
+	}
+
+	files := os.DirFS(overrideDir)
+
+	err := registry.loadFS(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	err = registry.loadExperiments(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+func newRegistry() *Registry {
+	return &Registry{
+		templates:   make(map[string]map[int]*template.Template),
+		pinned:      make(map[string]int),
+		experiments: make(map[string]Experiment),
+	}
+}
+
+func (registry *Registry) loadFS(files fs.FS, dir string) error {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read prompts: %v", err)
+	}
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		name := match[1]
+		version, _ := strconv.Atoi(match[2])
+
+		input, ok := inputs[name]
+		if !ok {
+			return fmt.Errorf("prompt %v: unknown prompt name", entry.Name())
+		}
+
+		data, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("prompt %v: %v", entry.Name(), err)
+		}
+
+		tmpl, err := template.New(entry.Name()).Funcs(functions).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("prompt %v: %v", entry.Name(), err)
+		}
+
+		// Catches fields that do not exist in the input before any request
+		err = tmpl.Execute(&bytes.Buffer{}, reflect.New(input).Elem().Interface())
+		if err != nil {
+			return fmt.Errorf("prompt %v: %v", entry.Name(), err)
+		}
+
+		if registry.templates[name] == nil {
+			registry.templates[name] = make(map[int]*template.Template)
+		}
+		registry.templates[name][version] = tmpl
+	}
+
+	return nil
+}
+
+// Pin makes name always render with the given version.
+func (registry *Registry) Pin(name string, version int) error {
+	if _, ok := registry.templates[name][version]; !ok {
+		return fmt.Errorf("prompt %v has no version %v", name, version)
+	}
+
+	registry.pinned[name] = version
+	return nil
+}
+
+func (registry *Registry) Names() []string {
+	names := make([]string, 0, len(registry.templates))
+	for name := range registry.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Versions lists the available versions of name from oldest to newest.
+func (registry *Registry) Versions(name string) []int {
+	versions := make([]int, 0, len(registry.templates[name]))
+	for version := range registry.templates[name] {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	return versions
+}
+
+// Version is the version Render uses for name: the pinned one or the newest.
+func (registry *Registry) Version(name string) int {
+	if version, ok := registry.pinned[name]; ok {
+		return version
+	}
+
+	versions := registry.Versions(name)
+	if len(versions) == 0 {
+		return 0
+	}
+	return versions[len(versions)-1]
+}
+
+func (registry *Registry) Render(name string, input any) (Prompt, error) {
+	return registry.RenderVersion(name, registry.Version(name), input)
+}
+
+// RenderVersion executes a specific version of the template. The input must
+// be the type declared for the prompt in inputs.
+func (registry *Registry) RenderVersion(name string, version int, input any) (Prompt, error) {
+	expected, ok := inputs[name]
+	if !ok {
+		return Prompt{}, fmt.Errorf("unknown prompt %v", name)
+	}
+	if reflect.TypeOf(input) != expected {
+		return Prompt{}, fmt.Errorf("prompt %v expects %v, got %T", name, expected, input)
+	}
+
+	tmpl, ok := registry.templates[name][version]
+	if !ok {
+		return Prompt{}, fmt.Errorf("prompt %v has no version %v", name, version)
+	}
+
+	var text strings.Builder
+	err := tmpl.Execute(&text, input)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to render prompt %v v%v: %v", name, version, err)
+	}
+
+	return Prompt{
+		Ref:  Ref{Name: name, Version: version},
+		Text: text.String(),
+	}, nil
+}
+
+// Loads the templates from PROMPTS_DIR and pins the versions set with
+// PROMPT_<NAME>_VERSION.
+func NewFromEnv() (*Registry, error) {
+	registry, err := Load(configs.GetPromptsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range registry.Names() {
+		version := configs.GetPromptVersion(name)
+		if version == 0 {
+			continue
+		}
+
+		err = registry.Pin(name, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
@@ -0,0 +1,322 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"prepai.app/configs"
+)
+
+const (
+	// Calls the provider and saves every interaction
+	CassetteRecord = "record"
+	// Answers from the cassettes only, unmatched requests fail
+	CassetteReplay = "replay"
+	// Replays what was recorded and records the rest
+	CassetteAuto = "auto"
+)
+
+var ErrUnmatchedRequest = errors.New("no recorded interaction matches the request")
+
+// Interaction is one request to the model and its response as saved on disk.
+// Files are stored by hash so cassettes stay small and readable.
+type Interaction struct {
+	Key        string           `json:"key"`
+	Feature    string           `json:"feature"`
+	Request    RecordedRequest  `json:"request"`
+	Config     any              `json:"config,omitempty"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+type RecordedRequest struct {
+	System string         `json:"system,omitempty"`
+	Parts  []RecordedPart `json:"parts"`
+}
+
+type RecordedPart struct {
+	Text     string `json:"text,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	Size     int    `json:"size,omitempty"`
+}
+
+type RecordedResponse struct {
+	Text             string `json:"text"`
+	Model            string `json:"model"`
+	PromptTokens     int32  `json:"prompt_tokens"`
+	CompletionTokens int32  `json:"completion_tokens"`
+}
+
+// Cassette records the interactions with a provider to a directory, one
+// <feature>.json file per feature, and replays them by a hash of the
+// normalized request. Tests and evaluations replay them without network.
+type Cassette struct {
+	dir      string
+	mode     string
+	provider Provider
+
+	// Optional, describes the settings a feature is generated with. It is
+	// saved next to each interaction and must not contain secrets.
+	Describe func(feature string) any
+
+	mutex        sync.Mutex
+	interactions map[string]map[string]Interaction
+}
+
+// NewCassette loads the cassettes in dir. The provider is only called when
+// recording and can be nil in replay mode.
+func NewCassette(dir string, mode string, provider Provider) (*Cassette, error) {
+	switch mode {
+	case CassetteRecord, CassetteAuto:
+		if provider == nil {
+			return nil, fmt.Errorf("cassette mode %v needs a provider to record from", mode)
+		}
+	case CassetteReplay:
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %v", mode)
+	}
+
+	cassette := &Cassette{
+		dir:          dir,
+		mode:         mode,
+		provider:     provider,
+		interactions: make(map[string]map[string]Interaction),
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %v", err)
+		}
+
+		var interactions []Interaction
+		err = json.Unmarshal(data, &interactions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %v: %v", filepath.Base(file), err)
+		}
+
+		for _, interaction := range interactions {
+			cassette.feature(interaction.Feature)[interaction.Key] = interaction
+		}
+	}
+
+	return cassette, nil
+}
+
+func (cassette *Cassette) feature(feature string) map[string]Interaction {
+	interactions, ok := cassette.interactions[feature]
+	if !ok {
+		interactions = make(map[string]Interaction)
+		cassette.interactions[feature] = interactions
+	}
+
+	return interactions
+}
+
+func (cassette *Cassette) Model(feature string) string {
+	if cassette.provider == nil {
+		return ""
+	}
+
+	return ModelFor(cassette.provider, feature)
+}
+
+func (cassette *Cassette) Generate(ctx context.Context, request Request) (Response, error) {
+	return cassette.play(request, func() (Response, error) {
+		return cassette.provider.Generate(ctx, request)
+	}, nil)
+}
+
+func (cassette *Cassette) Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error) {
+	return cassette.play(request, func() (Response, error) {
+		return Stream(ctx, cassette.provider, request, onChunk)
+	}, onChunk)
+}
+
+func (cassette *Cassette) play(request Request, call func() (Response, error), onChunk func(text string)) (Response, error) {
+	key := RequestKey(request)
+
+	if cassette.mode != CassetteRecord {
+		cassette.mutex.Lock()
+		interaction, ok := cassette.interactions[request.Feature][key]
+		cassette.mutex.Unlock()
+
+		if ok {
+			response := Response{
+				Text:             interaction.Response.Text,
+				Model:            interaction.Response.Model,
+				PromptTokens:     interaction.Response.PromptTokens,
+				CompletionTokens: interaction.Response.CompletionTokens,
+			}
+			if onChunk != nil {
+				onChunk(response.Text)
+			}
+			return response, nil
+		}
+
+		if cassette.mode == CassetteReplay {
+			err := fmt.Errorf("%w: %v request %v starting with %q", ErrUnmatchedRequest, request.Feature, key, preview(request))
+			log.Print(err)
+			return Response{}, err
+		}
+	}
+
+	response, err := call()
+	if err != nil {
+		return Response{}, err
+	}
+
+	err = cassette.record(key, request, response)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return response, nil
+}
+
+func (cassette *Cassette) record(key string, request Request, response Response) error {
+	interaction := Interaction{
+		Key:     key,
+		Feature: request.Feature,
+		Request: RecordedRequest{
+			System: request.System,
+			Parts:  make([]RecordedPart, len(request.Parts)),
+		},
+		Response: RecordedResponse{
+			Text:             response.Text,
+			Model:            response.Model,
+			PromptTokens:     response.PromptTokens,
+			CompletionTokens: response.CompletionTokens,
+		},
+		RecordedAt: time.Now().UTC(),
+	}
+	for i, part := range request.Parts {
+		interaction.Request.Parts[i] = RecordedPart{Text: part.Text, MIMEType: part.MIMEType}
+		if part.Data != nil {
+			sum := sha256.Sum256(part.Data)
+			interaction.Request.Parts[i].SHA256 = hex.EncodeToString(sum[:])
+			interaction.Request.Parts[i].Size = len(part.Data)
+		}
+	}
+	if cassette.Describe != nil {
+		interaction.Config = cassette.Describe(request.Feature)
+	}
+
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+
+	interactions := cassette.feature(request.Feature)
+	interactions[key] = interaction
+
+	// Sorted by key so recording again only changes what changed
+	sorted := make([]Interaction, 0, len(interactions))
+	for _, interaction := range interactions {
+		sorted = append(sorted, interaction)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+
+	data, err := json.MarshalIndent(sorted, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(cassette.dir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create cassette directory: %v", err)
+	}
+
+	return os.WriteFile(filepath.Join(cassette.dir, request.Feature+".json"), append(data, '\n'), 0o644)
+}
+
+// RequestKey hashes what the model sees of the request. Line endings and
+// trailing spaces are normalized so editing a template in another editor does
+// not invalidate the cassettes.
+func RequestKey(request Request) string {
+	hash := sha256.New()
+	write := func(value string) {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+
+	write(request.Feature)
+	write(normalize(request.System))
+	for _, part := range request.Parts {
+		write(normalize(part.Text))
+		write(part.MIMEType)
+		if part.Data != nil {
+			sum := sha256.Sum256(part.Data)
+			write(hex.EncodeToString(sum[:]))
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func normalize(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// The start of the last text part, to tell which request did not match.
+func preview(request Request) string {
+	for i := len(request.Parts) - 1; i >= 0; i-- {
+		if text := strings.TrimSpace(request.Parts[i].Text); text != "" {
+			if runes := []rune(text); len(runes) > 80 {
+				text = string(runes[:80])
+			}
+			return text
+		}
+	}
+
+	return ""
+}
+
+// Wraps the provider in a cassette when LLM_CASSETTE_DIR is set, e.g. to
+// record the interactions of a staging server.
+func WithCassetteFromEnv(provider Provider) (Provider, error) {
+	config := configs.GetCassetteConfig()
+	if config.Dir == "" {
+		return provider, nil
+	}
+
+	cassette, err := NewCassette(config.Dir, config.Mode, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette.Describe = func(feature string) any {
+		llmConfig := configs.GetLLMConfig(feature)
+		return map[string]any{
+			"backend":           llmConfig.Backend,
+			"base_url":          llmConfig.BaseURL,
+			"model":             llmConfig.Model,
+			"fallback_model":    llmConfig.FallbackModel,
+			"temperature":       llmConfig.Temperature,
+			"max_output_tokens": llmConfig.MaxOutputTokens,
+		}
+	}
+
+	return cassette, nil
+}
@@ -0,0 +1,288 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"prepai.app/internal"
+	"prepai.app/prompts"
+	"prepai.app/providers"
+)
+
+// Synthetic cassettes, recorded from the fake provider answering with the
+// responses of the evaluation suite. They test replaying and request keys,
+// not how a real model behaves.
+const cassettes = "testdata/synthetic"
+
+func replayGenerator(t *testing.T) *internal.Generator {
+	t.Helper()
+
+	cassette, err := providers.NewCassette(cassettes, providers.CassetteReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return internal.NewGenerator(cassette)
+}
+
+// Each generator is called with the inputs the cassettes were recorded with
+// in the evaluation suite, so prompt changes that would break the replay show
+// up here first.
+func TestCassetteReplay(t *testing.T) {
+	resume, err := os.ReadFile("../cmd/prepai-eval/fixtures/resume.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		generate func(ctx context.Context, generator *internal.Generator) (int, error)
+		want     int
+	}{
+		{"multiple-choice exam", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			exam, err := generator.GenerateExam(ctx, "Go concurrency", "easy", internal.MultipleChoice)
+			return len(exam.Questions), err
+		}, 10},
+		{"true-false exam", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			exam, err := generator.GenerateExam(ctx, "SQL joins", "easy", internal.TrueFalse)
+			return len(exam.Questions), err
+		}, 10},
+		{"multiple-select exam", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			exam, err := generator.GenerateExam(ctx, "Go basics", "easy", internal.MultipleSelect)
+			return len(exam.Questions), err
+		}, 10},
+		{"interview", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			interview, err := generator.GenerateInterview(ctx, "Backend developer", "junior", []string{"REST", "SQL"})
+			return len(interview.Questions), err
+		}, 5},
+		{"feedback", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			feedback, err := generator.GenerateInterviewFeedback(ctx, []internal.UserInterviewResponse{
+				{Question: "Tell me about a REST API you built.", Answer: "I built an orders API with Go and Postgres, exposing CRUD endpoints."},
+				{Question: "Why do you want to join us?", Answer: "I like the product."},
+			})
+			return len(feedback.Feedbacks), err
+		}, 2},
+		{"resume", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			analysis, err := generator.ResumeAnalyzer(ctx, resume, "Backend developer with Go and PostgreSQL experience.")
+			return int(analysis.OverallScore), err
+		}, 72},
+		{"question", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			analysis, err := generator.GenerateQuestionAnalysis(ctx, "Tell me about yourself")
+			return len(analysis.IdealAnswer.KeyPoints), err
+		}, 3},
+		{"modules", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			modules, err := generator.GenerateModules(ctx, "Backend developer", "junior", "Build APIs in Go.", []string{"Go", "SQL", "REST"})
+			return len(modules.Modules), err
+		}, 11},
+		{"steps after a repair", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			steps, err := generator.GenerateSteps(ctx, "Goroutines", "Run work concurrently", []string{"goroutines", "sync"})
+			return len(steps.Steps), err
+		}, 9},
+		{"lesson", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			lesson, err := generator.GenerateLesson(ctx, "Channels", "easy", []string{"buffered channels", "select"})
+			return len(lesson.Sections), err
+		}, 3},
+		{"translated exam", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			exam, err := generator.TranslateExam(internal.WithLanguage(ctx, "es"), internal.ExamResponse{
+				Title: "Go Slices",
+				Questions: []internal.ExamQuestion{
+					{Question: "What does len return for a slice?", Options: []string{"Its number of elements", "Its capacity", "Its size in bytes", "The index of its last element"}, Correct: 0, Explanation: "len returns how many elements the slice holds."},
+					{Question: "What happens when append exceeds the capacity of a slice?", Options: []string{"It panics", "A larger array is allocated", "The extra elements are dropped", "The slice becomes nil"}, Correct: 1, Explanation: "append allocates a bigger backing array and copies the elements."},
+					{Question: "Which expression creates a slice with length 0 and capacity 10?", Options: []string{"make([]int, 10)", "new([]int)", "make([]int, 0, 10)", "[]int{10}"}, Correct: 2, Explanation: "make takes the length and then the capacity."},
+				},
+			}, internal.MultipleChoice, "es")
+			return len(exam.Questions), err
+		}, 3},
+		{"short answer grades", func(ctx context.Context, generator *internal.Generator) (int, error) {
+			grades, err := generator.GradeShortAnswers(ctx, []prompts.ShortAnswer{
+				{
+					Question:        "What does a buffered channel do when it is full?",
+					ReferenceAnswer: "Sends block until a receiver takes a value and frees space in the buffer.",
+					Answer:          "The sender waits until someone reads from the channel.",
+				},
+				{
+					Question:        "Why should a goroutine that ranges over a channel expect it to be closed?",
+					ReferenceAnswer: "Ranging over a channel only ends when the channel is closed, otherwise the loop blocks forever.",
+					Answer:          "Because closing it makes it faster.",
+				},
+			})
+			return len(grades.Grades), err
+		}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.generate(context.Background(), replayGenerator(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCassetteReplayUnmatched(t *testing.T) {
+	cassette, err := providers.NewCassette(cassettes, providers.CassetteReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cassette.Generate(context.Background(), providers.Request{
+		Feature: providers.FeatureExam,
+		Parts:   []providers.Part{{Text: "A prompt nobody recorded"}},
+	})
+	if !errors.Is(err, providers.ErrUnmatchedRequest) {
+		t.Fatalf("got %v, want %v", err, providers.ErrUnmatchedRequest)
+	}
+
+	_, err = providers.Stream(context.Background(), cassette, providers.Request{Feature: "unknown"}, func(string) {})
+	if !errors.Is(err, providers.ErrUnmatchedRequest) {
+		t.Fatalf("streaming got %v, want %v", err, providers.ErrUnmatchedRequest)
+	}
+
+	// Generators hand the error back as is
+	_, err = internal.NewGenerator(cassette).GenerateQuestionAnalysis(context.Background(), "Where do you see yourself in five years?")
+	if !errors.Is(err, providers.ErrUnmatchedRequest) {
+		t.Fatalf("generator got %v, want %v", err, providers.ErrUnmatchedRequest)
+	}
+}
+
+func TestCassetteRecord(t *testing.T) {
+	dir := t.TempDir()
+	request := providers.Request{
+		Feature: providers.FeatureLesson,
+		System:  "Answer in English",
+		Parts:   []providers.Part{{MIMEType: "application/pdf", Data: []byte("%PDF")}, {Text: "Write a lesson"}},
+	}
+
+	recorder, err := providers.NewCassette(dir, providers.CassetteRecord, providers.NewFake(`{"title": "Lesson"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = recorder.Generate(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := providers.NewCassette(dir, providers.CassetteReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := replay.Generate(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != `{"title": "Lesson"}` {
+		t.Fatalf("replayed %q", response.Text)
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	request := providers.Request{
+		Feature: providers.FeatureExam,
+		System:  "You are an interviewer.\nAnswer in English.",
+		Parts: []providers.Part{
+			{MIMEType: "application/pdf", Data: []byte("%PDF-1.4")},
+			{Text: "Create an exam.\n\t- With 10 questions."},
+		},
+	}
+	key := providers.RequestKey(request)
+
+	equivalent := []struct {
+		name    string
+		request providers.Request
+	}{
+		{"same request", request},
+		{"copied data", providers.Request{
+			Feature: request.Feature,
+			System:  request.System,
+			Parts: []providers.Part{
+				{MIMEType: "application/pdf", Data: []byte(string(request.Parts[0].Data))},
+				request.Parts[1],
+			},
+		}},
+		{"windows line endings", providers.Request{
+			Feature: request.Feature,
+			System:  "You are an interviewer.\r\nAnswer in English.",
+			Parts:   []providers.Part{request.Parts[0], {Text: "Create an exam.\r\n\t- With 10 questions."}},
+		}},
+		{"trailing spaces", providers.Request{
+			Feature: request.Feature,
+			System:  "You are an interviewer.  \nAnswer in English.\t",
+			Parts:   []providers.Part{request.Parts[0], {Text: "\nCreate an exam. \n\t- With 10 questions.\n\n"}},
+		}},
+	}
+	for _, test := range equivalent {
+		if got := providers.RequestKey(test.request); got != key {
+			t.Errorf("%v: key %v, want %v", test.name, got, key)
+		}
+	}
+
+	different := []struct {
+		name    string
+		request providers.Request
+	}{
+		{"feature", providers.Request{Feature: providers.FeatureInterview, System: request.System, Parts: request.Parts}},
+		{"system", providers.Request{Feature: request.Feature, System: "Answer in Spanish.", Parts: request.Parts}},
+		{"text", providers.Request{Feature: request.Feature, System: request.System, Parts: []providers.Part{request.Parts[0], {Text: "Create an exam.\n- With 10 questions."}}}},
+		{"data", providers.Request{Feature: request.Feature, System: request.System, Parts: []providers.Part{{MIMEType: "application/pdf", Data: []byte("%PDF-1.5")}, request.Parts[1]}}},
+		{"mime type", providers.Request{Feature: request.Feature, System: request.System, Parts: []providers.Part{{MIMEType: "image/png", Data: request.Parts[0].Data}, request.Parts[1]}}},
+		{"part boundaries", providers.Request{Feature: request.Feature, System: request.System, Parts: []providers.Part{request.Parts[0], {Text: "Create an exam."}, {Text: "\t- With 10 questions."}}}},
+	}
+	for _, test := range different {
+		if providers.RequestKey(test.request) == key {
+			t.Errorf("%v: key did not change", test.name)
+		}
+	}
+}
+
+// Keys saved in the cassettes must keep matching the requests they recorded,
+// otherwise every replay breaks at once.
+func TestRequestKeyMatchesCassettes(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(cassettes, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checked := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var interactions []providers.Interaction
+		err = json.Unmarshal(data, &interactions)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, interaction := range interactions {
+			request := providers.Request{Feature: interaction.Feature, System: interaction.Request.System}
+			files := false
+			for _, part := range interaction.Request.Parts {
+				files = files || part.SHA256 != ""
+				request.Parts = append(request.Parts, providers.Part{Text: part.Text, MIMEType: part.MIMEType})
+			}
+			// Only the hash of files is saved, they are checked by the replay
+			if files {
+				continue
+			}
+
+			if got := providers.RequestKey(request); got != interaction.Key {
+				t.Errorf("%v: key %v, recorded %v", filepath.Base(file), got, interaction.Key)
+			}
+			checked++
+		}
+	}
+
+	if checked == 0 {
+		t.Fatal("no interactions checked")
+	}
+}
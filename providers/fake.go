@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Fake is a deterministic provider for offline tests. It answers with the
+// queued responses in order, or with Handler when the queue is empty, and
+// records every request it receives.
+type Fake struct {
+	Handler func(request Request) (string, error)
+
+	mutex     sync.Mutex
+	responses []string
+	requests  []Request
+}
+
+func NewFake(responses ...string) *Fake {
+	return &Fake{responses: responses}
+}
+
+func (fake *Fake) Push(responses ...string) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	fake.responses = append(fake.responses, responses...)
+}
+
+func (fake *Fake) Requests() []Request {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	requests := make([]Request, len(fake.requests))
+	copy(requests, fake.requests)
+	return requests
+}
+
+func (fake *Fake) Model(feature string) string {
+	return "fake"
+}
+
+func (fake *Fake) Generate(ctx context.Context, request Request) (Response, error) {
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+
+	fake.mutex.Lock()
+	fake.requests = append(fake.requests, request)
+
+	var text string
+	var err error
+	if len(fake.responses) > 0 {
+		text = fake.responses[0]
+		fake.responses = fake.responses[1:]
+		fake.mutex.Unlock()
+	} else {
+		fake.mutex.Unlock()
+		if fake.Handler == nil {
+			return Response{}, errors.New("fake provider has no response queued")
+		}
+		text, err = fake.Handler(request)
+		if err != nil {
+			return Response{}, err
+		}
+	}
+
+	return Response{
+		Text:             text,
+		Model:            "fake",
+		PromptTokens:     int32(promptLength(request) / 4),
+		CompletionTokens: int32(len(text) / 4),
+	}, nil
+}
+
+// Stream hands out the next response in small chunks, like a real model would.
+func (fake *Fake) Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error) {
+	response, err := fake.Generate(ctx, request)
+	if err != nil {
+		return Response{}, err
+	}
+
+	const chunkSize = 32
+	for start := 0; start < len(response.Text); start += chunkSize {
+		end := min(start+chunkSize, len(response.Text))
+		onChunk(response.Text[start:end])
+	}
+
+	return response, nil
+}
+
+func promptLength(request Request) int {
+	length := 0
+	for _, part := range request.Parts {
+		length += len(part.Text) + len(part.Data)
+	}
+
+	return length
+}
@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"google.golang.org/genai"
+	"prepai.app/configs"
+)
+
+type Gemini struct {
+	client *genai.Client
+	config configs.GeminiConfig
+}
+
+// genai clients are safe to share, features using other models with the same
+// key reuse the same one.
+var (
+	geminiClientsMutex sync.Mutex
+	geminiClients      = make(map[string]*genai.Client)
+)
+
+func NewGemini(config configs.GeminiConfig) (*Gemini, error) {
+	geminiClientsMutex.Lock()
+	defer geminiClientsMutex.Unlock()
+
+	client, ok := geminiClients[config.APIKey]
+	if !ok {
+		var err error
+		client, err = genai.NewClient(context.Background(), &genai.ClientConfig{
+			APIKey:  config.APIKey,
+			Backend: genai.BackendGeminiAPI,
+		})
+		if err != nil {
+			return nil, err
+		}
+		geminiClients[config.APIKey] = client
+	}
+
+	return &Gemini{client: client, config: config}, nil
+}
+
+func (gemini *Gemini) Model(feature string) string {
+	return gemini.config.ModelName
+}
+
+func (gemini *Gemini) Generate(ctx context.Context, request Request) (Response, error) {
+	contents, config := gemini.build(request)
+
+	result, err := gemini.client.Models.GenerateContent(ctx, gemini.config.ModelName, contents, config)
+	if err != nil {
+		return Response{}, geminiError(err)
+	}
+
+	response := Response{
+		Text:  result.Text(),
+		Model: gemini.config.ModelName,
+	}
+	if result.UsageMetadata != nil {
+		response.PromptTokens = result.UsageMetadata.PromptTokenCount
+		response.CompletionTokens = result.UsageMetadata.CandidatesTokenCount
+	}
+
+	return response, nil
+}
+
+func (gemini *Gemini) Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error) {
+	contents, config := gemini.build(request)
+
+	var text strings.Builder
+	response := Response{Model: gemini.config.ModelName}
+
+	for result, err := range gemini.client.Models.GenerateContentStream(ctx, gemini.config.ModelName, contents, config) {
+		if err != nil {
+			return Response{}, geminiError(err)
+		}
+
+		chunk := result.Text()
+		if chunk != "" {
+			text.WriteString(chunk)
+			onChunk(chunk)
+		}
+		// Every chunk carries the running totals, the last one wins
+		if result.UsageMetadata != nil {
+			response.PromptTokens = result.UsageMetadata.PromptTokenCount
+			response.CompletionTokens = result.UsageMetadata.CandidatesTokenCount
+		}
+	}
+
+	response.Text = text.String()
+	return response, nil
+}
+
+// API errors become StatusError so every backend is retried the same way.
+func geminiError(err error) error {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return &StatusError{StatusCode: apiErr.Code, Message: apiErr.Message}
+	}
+
+	return err
+}
+
+func (gemini *Gemini) build(request Request) ([]*genai.Content, *genai.GenerateContentConfig) {
+	parts := make([]*genai.Part, len(request.Parts))
+	for i, part := range request.Parts {
+		if part.Data != nil {
+			parts[i] = &genai.Part{
+				InlineData: &genai.Blob{
+					MIMEType: part.MIMEType,
+					Data:     part.Data,
+				},
+			}
+			continue
+		}
+		parts[i] = genai.NewPartFromText(part.Text)
+	}
+	contents := []*genai.Content{
+		genai.NewContentFromParts(parts, genai.RoleUser),
+	}
+
+	var topK *float32
+	if gemini.config.TopK != nil {
+		k := float32(*gemini.config.TopK)
+		topK = &k
+	}
+
+	var maxOutputTokens int32
+	if gemini.config.MaxOutputTokens != nil {
+		maxOutputTokens = *gemini.config.MaxOutputTokens
+	}
+
+	config := &genai.GenerateContentConfig{
+		Temperature:      gemini.config.Temperature,
+		TopP:             gemini.config.TopP,
+		TopK:             topK,
+		MaxOutputTokens:  maxOutputTokens,
+		StopSequences:    gemini.config.StopSequences,
+		ResponseMIMEType: "application/json",
+		SafetySettings:   gemini.config.SafeSettings,
+	}
+
+	if request.System != "" {
+		config.SystemInstruction = genai.NewContentFromText(request.System, genai.RoleUser)
+	}
+
+	return contents, config
+}
@@ -0,0 +1,192 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"prepai.app/configs"
+)
+
+var errBusy = errors.New("timed out waiting for a free slot")
+
+// Limiter is shared by the whole process and caps the calls in flight to
+// each model with a weighted semaphore, so bursts wait in line instead of
+// hitting the provider's rate limits.
+type Limiter struct {
+	config configs.LimiterConfig
+
+	mutex  sync.Mutex
+	models map[string]*modelLimit
+}
+
+type modelLimit struct {
+	semaphore *semaphore.Weighted
+
+	mutex     sync.Mutex
+	inFlight  int64
+	queued    int64
+	acquired  int64
+	timeouts  int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// LimiterStats describes the queue of one model since the process started.
+// InFlight and Queued count calls, Capacity is in weight.
+type LimiterStats struct {
+	Model         string  `json:"model"`
+	Capacity      int64   `json:"capacity"`
+	InFlight      int64   `json:"in_flight"`
+	Queued        int64   `json:"queued"`
+	Acquired      int64   `json:"acquired"`
+	Timeouts      int64   `json:"timeouts"`
+	AverageWaitMs float64 `json:"average_wait_ms"`
+	MaxWaitMs     float64 `json:"max_wait_ms"`
+}
+
+func NewLimiter(config configs.LimiterConfig) *Limiter {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+
+	return &Limiter{config: config, models: make(map[string]*modelLimit)}
+}
+
+// Wrap returns a provider that takes a slot of its model before each call.
+func (limiter *Limiter) Wrap(provider Provider) *Limited {
+	return &Limited{provider: provider, limiter: limiter}
+}
+
+// Files such as PDF resumes make much longer calls than short prompts.
+func (limiter *Limiter) weight(request Request) int64 {
+	weight := int64(1)
+	for _, part := range request.Parts {
+		if part.Data != nil {
+			weight += limiter.config.FileWeight
+		}
+	}
+
+	return min(weight, limiter.config.Concurrency)
+}
+
+func (limiter *Limiter) model(name string) *modelLimit {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	model, ok := limiter.models[name]
+	if !ok {
+		model = &modelLimit{semaphore: semaphore.NewWeighted(limiter.config.Concurrency)}
+		limiter.models[name] = model
+	}
+
+	return model
+}
+
+// Waits for the request's weight to be free on the model, for at most the
+// queue timeout. The returned function gives the slot back.
+func (limiter *Limiter) acquire(ctx context.Context, name string, request Request) (func(), error) {
+	model := limiter.model(name)
+	weight := limiter.weight(request)
+
+	model.mutex.Lock()
+	model.queued++
+	model.mutex.Unlock()
+
+	start := time.Now()
+	waitCtx, cancel := context.WithTimeout(ctx, limiter.config.QueueTimeout)
+	err := model.semaphore.Acquire(waitCtx, weight)
+	cancel()
+	wait := time.Since(start)
+
+	model.mutex.Lock()
+	defer model.mutex.Unlock()
+
+	model.queued--
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		model.timeouts++
+		return nil, errBusy
+	}
+
+	model.inFlight++
+	model.acquired++
+	model.totalWait += wait
+	model.maxWait = max(model.maxWait, wait)
+
+	return func() {
+		model.mutex.Lock()
+		model.inFlight--
+		model.mutex.Unlock()
+
+		model.semaphore.Release(weight)
+	}, nil
+}
+
+// Stats returns the queue of every model called so far, sorted by model.
+func (limiter *Limiter) Stats() []LimiterStats {
+	limiter.mutex.Lock()
+	names := make([]string, 0, len(limiter.models))
+	for name := range limiter.models {
+		names = append(names, name)
+	}
+	limiter.mutex.Unlock()
+	sort.Strings(names)
+
+	stats := make([]LimiterStats, len(names))
+	for i, name := range names {
+		model := limiter.model(name)
+
+		model.mutex.Lock()
+		stats[i] = LimiterStats{
+			Model:     name,
+			Capacity:  limiter.config.Concurrency,
+			InFlight:  model.inFlight,
+			Queued:    model.queued,
+			Acquired:  model.acquired,
+			Timeouts:  model.timeouts,
+			MaxWaitMs: float64(model.maxWait) / float64(time.Millisecond),
+		}
+		if model.acquired > 0 {
+			stats[i].AverageWaitMs = float64(model.totalWait) / float64(model.acquired) / float64(time.Millisecond)
+		}
+		model.mutex.Unlock()
+	}
+
+	return stats
+}
+
+// Limited is a provider whose calls go through the Limiter.
+type Limited struct {
+	provider Provider
+	limiter  *Limiter
+}
+
+func (limited *Limited) Model(feature string) string {
+	return ModelFor(limited.provider, feature)
+}
+
+func (limited *Limited) Generate(ctx context.Context, request Request) (Response, error) {
+	release, err := limited.limiter.acquire(ctx, limited.Model(request.Feature), request)
+	if err != nil {
+		return Response{}, err
+	}
+	defer release()
+
+	return limited.provider.Generate(ctx, request)
+}
+
+func (limited *Limited) Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error) {
+	release, err := limited.limiter.acquire(ctx, limited.Model(request.Feature), request)
+	if err != nil {
+		return Response{}, err
+	}
+	defer release()
+
+	return Stream(ctx, limited.provider, request, onChunk)
+}
@@ -0,0 +1,227 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"prepai.app/configs"
+)
+
+// Ollama talks to a local Ollama server through its /api/chat endpoint.
+type Ollama struct {
+	client *http.Client
+	config configs.LLMConfig
+}
+
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Format   string          `json:"format"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int32  `json:"prompt_eval_count"`
+	EvalCount       int32  `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+func NewOllama(config configs.LLMConfig) *Ollama {
+	return &Ollama{
+		client: &http.Client{Timeout: 5 * time.Minute},
+		config: config,
+	}
+}
+
+func (ollama *Ollama) Model(feature string) string {
+	return ollama.config.Model
+}
+
+func (ollama *Ollama) Generate(ctx context.Context, request Request) (Response, error) {
+	httpRequest, err := ollama.newRequest(ctx, request, false)
+	if err != nil {
+		return Response{}, err
+	}
+
+	httpResponse, err := ollama.client.Do(httpRequest)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return Response{}, ollamaStatusError(httpResponse)
+	}
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	var result ollamaResponse
+	err = json.Unmarshal(responseBody, &result)
+	if err != nil {
+		return Response{}, fmt.Errorf("ollama: unexpected response: %v", err)
+	}
+	if result.Error != "" {
+		return Response{}, &StatusError{StatusCode: httpResponse.StatusCode, Message: result.Error}
+	}
+
+	model := result.Model
+	if model == "" {
+		model = ollama.config.Model
+	}
+
+	return Response{
+		Text:             result.Message.Content,
+		Model:            model,
+		PromptTokens:     result.PromptEvalCount,
+		CompletionTokens: result.EvalCount,
+	}, nil
+}
+
+// Stream reads the newline delimited JSON objects Ollama sends with stream=true.
+// The last one carries the token counts.
+func (ollama *Ollama) Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error) {
+	httpRequest, err := ollama.newRequest(ctx, request, true)
+	if err != nil {
+		return Response{}, err
+	}
+
+	httpResponse, err := ollama.client.Do(httpRequest)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return Response{}, ollamaStatusError(httpResponse)
+	}
+
+	var text strings.Builder
+	response := Response{Model: ollama.config.Model}
+
+	scanner := bufio.NewScanner(httpResponse.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaResponse
+		err = json.Unmarshal(line, &chunk)
+		if err != nil {
+			return Response{}, fmt.Errorf("ollama: unexpected stream chunk (status %v)", httpResponse.StatusCode)
+		}
+		if chunk.Error != "" {
+			return Response{}, &StatusError{StatusCode: httpResponse.StatusCode, Message: chunk.Error}
+		}
+
+		if chunk.Model != "" {
+			response.Model = chunk.Model
+		}
+		if chunk.Message.Content != "" {
+			text.WriteString(chunk.Message.Content)
+			onChunk(chunk.Message.Content)
+		}
+		if chunk.Done {
+			response.PromptTokens = chunk.PromptEvalCount
+			response.CompletionTokens = chunk.EvalCount
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Response{}, err
+	}
+
+	response.Text = text.String()
+	return response, nil
+}
+
+// The error of a failed response. Ollama sends the message as JSON, proxies in
+// front of it may send HTML, which only keeps the status.
+func ollamaStatusError(httpResponse *http.Response) error {
+	var result ollamaResponse
+	responseBody, _ := io.ReadAll(httpResponse.Body)
+	if json.Unmarshal(responseBody, &result) == nil && result.Error != "" {
+		return &StatusError{StatusCode: httpResponse.StatusCode, Message: result.Error}
+	}
+	return &StatusError{StatusCode: httpResponse.StatusCode, Message: http.StatusText(httpResponse.StatusCode)}
+}
+
+func (ollama *Ollama) newRequest(ctx context.Context, request Request, stream bool) (*http.Request, error) {
+	var text strings.Builder
+	var images []string
+
+	for _, part := range request.Parts {
+		switch {
+		case part.Data == nil:
+			text.WriteString(part.Text)
+		case strings.HasPrefix(part.MIMEType, "text/"):
+			text.Write(part.Data)
+		case strings.HasPrefix(part.MIMEType, "image/"):
+			images = append(images, base64.StdEncoding.EncodeToString(part.Data))
+		case part.MIMEType == "application/pdf":
+			// Ollama models cannot read PDFs, so the text is sent instead
+			text.WriteString("\nDocument content:\n")
+			text.WriteString(ExtractPDFText(part.Data))
+		default:
+			return nil, fmt.Errorf("ollama: unsupported part type %v", part.MIMEType)
+		}
+		text.WriteString("\n")
+	}
+
+	options := map[string]any{}
+	if ollama.config.Temperature != nil {
+		options["temperature"] = *ollama.config.Temperature
+	}
+	if ollama.config.MaxOutputTokens != nil {
+		options["num_predict"] = *ollama.config.MaxOutputTokens
+	}
+
+	var messages []ollamaMessage
+	if request.System != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: request.System})
+	}
+	messages = append(messages, ollamaMessage{Role: "user", Content: text.String(), Images: images})
+
+	body, err := json.Marshal(ollamaRequest{
+		Model:    ollama.config.Model,
+		Messages: messages,
+		Format:   "json",
+		Stream:   stream,
+		Options:  options,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(ollama.config.BaseURL, "/") + "/api/chat"
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	return httpRequest, nil
+}
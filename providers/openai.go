@@ -0,0 +1,271 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"prepai.app/configs"
+)
+
+// OpenAI talks to any server implementing the OpenAI chat-completions
+// protocol (OpenAI, Azure, vLLM, LM Studio, OpenRouter...).
+type OpenAI struct {
+	client *http.Client
+	config configs.LLMConfig
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+type openAIRequest struct {
+	Model          string            `json:"model"`
+	Messages       []openAIMessage   `json:"messages"`
+	ResponseFormat map[string]string `json:"response_format"`
+	Temperature    *float32          `json:"temperature,omitempty"`
+	MaxTokens      *int32            `json:"max_tokens,omitempty"`
+	Stream         bool              `json:"stream,omitempty"`
+	StreamOptions  map[string]bool   `json:"stream_options,omitempty"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int32 `json:"prompt_tokens"`
+		CompletionTokens int32 `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type openAIChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int32 `json:"prompt_tokens"`
+		CompletionTokens int32 `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewOpenAI(config configs.LLMConfig) *OpenAI {
+	return &OpenAI{
+		client: &http.Client{Timeout: 3 * time.Minute},
+		config: config,
+	}
+}
+
+func (openAI *OpenAI) Model(feature string) string {
+	return openAI.config.Model
+}
+
+func (openAI *OpenAI) Generate(ctx context.Context, request Request) (Response, error) {
+	httpRequest, err := openAI.newRequest(ctx, request, false)
+	if err != nil {
+		return Response{}, err
+	}
+
+	httpResponse, err := openAI.client.Do(httpRequest)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		var result openAIResponse
+		responseBody, _ := io.ReadAll(httpResponse.Body)
+		if json.Unmarshal(responseBody, &result) == nil && result.Error != nil {
+			return Response{}, &StatusError{StatusCode: httpResponse.StatusCode, Message: result.Error.Message}
+		}
+		return Response{}, &StatusError{StatusCode: httpResponse.StatusCode, Message: http.StatusText(httpResponse.StatusCode)}
+	}
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	var result openAIResponse
+	err = json.Unmarshal(responseBody, &result)
+	if err != nil {
+		return Response{}, fmt.Errorf("openai: unexpected response: %v", err)
+	}
+	if result.Error != nil {
+		return Response{}, &StatusError{StatusCode: httpResponse.StatusCode, Message: result.Error.Message}
+	}
+	if len(result.Choices) == 0 {
+		return Response{}, fmt.Errorf("openai: response has no choices")
+	}
+
+	model := result.Model
+	if model == "" {
+		model = openAI.config.Model
+	}
+
+	return Response{
+		Text:             result.Choices[0].Message.Content,
+		Model:            model,
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
+	}, nil
+}
+
+// Stream reads the server-sent events of a chat completion with stream=true.
+func (openAI *OpenAI) Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error) {
+	httpRequest, err := openAI.newRequest(ctx, request, true)
+	if err != nil {
+		return Response{}, err
+	}
+
+	httpResponse, err := openAI.client.Do(httpRequest)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		var result openAIResponse
+		responseBody, _ := io.ReadAll(httpResponse.Body)
+		if json.Unmarshal(responseBody, &result) == nil && result.Error != nil {
+			return Response{}, &StatusError{StatusCode: httpResponse.StatusCode, Message: result.Error.Message}
+		}
+		return Response{}, &StatusError{StatusCode: httpResponse.StatusCode, Message: http.StatusText(httpResponse.StatusCode)}
+	}
+
+	var text strings.Builder
+	response := Response{Model: openAI.config.Model}
+
+	scanner := bufio.NewScanner(httpResponse.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChunk
+		err = json.Unmarshal([]byte(data), &chunk)
+		if err != nil {
+			return Response{}, fmt.Errorf("openai: unexpected stream chunk: %v", err)
+		}
+		if chunk.Error != nil {
+			return Response{}, &StatusError{StatusCode: httpResponse.StatusCode, Message: chunk.Error.Message}
+		}
+
+		if chunk.Model != "" {
+			response.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			response.PromptTokens = chunk.Usage.PromptTokens
+			response.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			text.WriteString(chunk.Choices[0].Delta.Content)
+			onChunk(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Response{}, err
+	}
+
+	response.Text = text.String()
+	return response, nil
+}
+
+func (openAI *OpenAI) newRequest(ctx context.Context, request Request, stream bool) (*http.Request, error) {
+	content := make([]map[string]any, 0, len(request.Parts))
+	for _, part := range request.Parts {
+		switch {
+		case part.Data == nil:
+			content = append(content, map[string]any{"type": "text", "text": part.Text})
+		case strings.HasPrefix(part.MIMEType, "text/"):
+			content = append(content, map[string]any{"type": "text", "text": string(part.Data)})
+		case strings.HasPrefix(part.MIMEType, "image/"):
+			content = append(content, map[string]any{
+				"type":      "image_url",
+				"image_url": map[string]string{"url": dataURL(part)},
+			})
+		default:
+			content = append(content, map[string]any{
+				"type": "file",
+				"file": map[string]string{
+					"filename":  "document" + extensionFor(part.MIMEType),
+					"file_data": dataURL(part),
+				},
+			})
+		}
+	}
+
+	var messages []openAIMessage
+	if request.System != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: request.System})
+	}
+	messages = append(messages, openAIMessage{Role: "user", Content: content})
+
+	payload := openAIRequest{
+		Model:          openAI.config.Model,
+		Messages:       messages,
+		ResponseFormat: map[string]string{"type": "json_object"},
+		Temperature:    openAI.config.Temperature,
+		MaxTokens:      openAI.config.MaxOutputTokens,
+	}
+	if stream {
+		payload.Stream = true
+		payload.StreamOptions = map[string]bool{"include_usage": true}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(openAI.config.BaseURL, "/") + "/chat/completions"
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if openAI.config.APIKey != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+openAI.config.APIKey)
+	}
+
+	return httpRequest, nil
+}
+
+func dataURL(part Part) string {
+	return "data:" + part.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(part.Data)
+}
+
+func extensionFor(mimeType string) string {
+	switch mimeType {
+	case "application/pdf":
+		return ".pdf"
+	default:
+		return ""
+	}
+}
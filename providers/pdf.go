@@ -0,0 +1,240 @@
+package providers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ExtractPDFText is a best-effort text extractor for backends that cannot read
+// PDF files. It inflates the content streams and collects the strings shown by
+// the text operators, which covers resumes exported by common editors. Fonts
+// with custom encodings may come out garbled.
+func ExtractPDFText(data []byte) string {
+	var text strings.Builder
+
+	pos := 0
+	for {
+		index := bytes.Index(data[pos:], []byte("stream"))
+		if index < 0 {
+			break
+		}
+		keyword := pos + index
+		pos = keyword + len("stream")
+
+		if keyword > 0 && data[keyword-1] == 'd' {
+			continue
+		}
+
+		start := pos
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+
+		length := bytes.Index(data[start:], []byte("endstream"))
+		if length < 0 {
+			break
+		}
+		stream := data[start : start+length]
+		pos = start + length + len("endstream")
+
+		dictionaryStart := keyword - 512
+		if dictionaryStart < 0 {
+			dictionaryStart = 0
+		}
+		dictionary := data[dictionaryStart:keyword]
+		if objIndex := bytes.LastIndex(dictionary, []byte("obj")); objIndex >= 0 {
+			dictionary = dictionary[objIndex:]
+		}
+
+		if bytes.Contains(dictionary, []byte("FlateDecode")) {
+			reader, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			// Truncated streams still return whatever was inflated
+			stream, _ = io.ReadAll(reader)
+			reader.Close()
+		} else if bytes.Contains(dictionary, []byte("Filter")) {
+			continue
+		}
+
+		if !bytes.Contains(stream, []byte("BT")) {
+			continue
+		}
+
+		extractStreamText(stream, &text)
+	}
+
+	return strings.TrimSpace(text.String())
+}
+
+func extractStreamText(stream []byte, text *strings.Builder) {
+	newline := func() {
+		current := text.String()
+		if len(current) > 0 && !strings.HasSuffix(current, "\n") {
+			text.WriteString("\n")
+		}
+	}
+
+	inArray := false
+	for i := 0; i < len(stream); i++ {
+		c := stream[i]
+
+		switch {
+		case c == '(':
+			var literal []byte
+			i, literal = readLiteral(stream, i+1)
+			text.Write(literal)
+
+		case c == '<' && i+1 < len(stream) && stream[i+1] == '<':
+			i++
+
+		case c == '<':
+			end := bytes.IndexByte(stream[i:], '>')
+			if end < 0 {
+				return
+			}
+			text.WriteString(decodeHexString(stream[i+1 : i+end]))
+			i += end
+
+		case c == '[':
+			inArray = true
+
+		case c == ']':
+			inArray = false
+
+		case c == '%':
+			end := bytes.IndexAny(stream[i:], "\r\n")
+			if end < 0 {
+				return
+			}
+			i += end
+
+		case c == '/':
+			for i+1 < len(stream) && !isDelimiter(stream[i+1]) {
+				i++
+			}
+
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			for i+1 < len(stream) && (stream[i+1] == '.' || (stream[i+1] >= '0' && stream[i+1] <= '9')) {
+				i++
+			}
+			// Big negative kerning inside TJ arrays separates words
+			if inArray {
+				value, err := strconv.ParseFloat(string(stream[start:i+1]), 64)
+				if err == nil && value < -200 {
+					text.WriteString(" ")
+				}
+			}
+
+		case (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || c == '\'' || c == '"' || c == '*':
+			start := i
+			for i+1 < len(stream) && !isDelimiter(stream[i+1]) {
+				i++
+			}
+			switch string(stream[start : i+1]) {
+			case "Td", "TD", "T*", "'", "\"", "ET":
+				newline()
+			case "Tj", "TJ":
+				text.WriteString(" ")
+			}
+		}
+	}
+}
+
+func readLiteral(stream []byte, i int) (int, []byte) {
+	var literal []byte
+	depth := 1
+
+	for ; i < len(stream); i++ {
+		c := stream[i]
+		switch c {
+		case '\\':
+			if i+1 >= len(stream) {
+				return i, literal
+			}
+			i++
+			switch stream[i] {
+			case 'n':
+				literal = append(literal, '\n')
+			case 'r', 't', 'b', 'f':
+				literal = append(literal, ' ')
+			case '\r', '\n':
+			default:
+				if stream[i] >= '0' && stream[i] <= '7' {
+					end := i
+					for end < len(stream) && end < i+3 && stream[end] >= '0' && stream[end] <= '7' {
+						end++
+					}
+					value, _ := strconv.ParseUint(string(stream[i:end]), 8, 8)
+					if value >= 32 && value < 127 {
+						literal = append(literal, byte(value))
+					}
+					i = end - 1
+				} else {
+					literal = append(literal, stream[i])
+				}
+			}
+		case '(':
+			depth++
+			literal = append(literal, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, literal
+			}
+			literal = append(literal, c)
+		default:
+			literal = append(literal, c)
+		}
+	}
+
+	return i, literal
+}
+
+func decodeHexString(value []byte) string {
+	clean := bytes.Map(func(r rune) rune {
+		if strings.ContainsRune(" \t\r\n", r) {
+			return -1
+		}
+		return r
+	}, value)
+	if len(clean)%2 == 1 {
+		clean = append(clean, '0')
+	}
+
+	decoded, err := hex.DecodeString(string(clean))
+	if err != nil {
+		return ""
+	}
+
+	// Two byte glyph ids only make sense when the high byte is empty
+	if len(decoded)%2 == 0 && len(decoded) > 0 && decoded[0] == 0 {
+		single := make([]byte, 0, len(decoded)/2)
+		for i := 1; i < len(decoded); i += 2 {
+			single = append(single, decoded[i])
+		}
+		decoded = single
+	}
+
+	var printable strings.Builder
+	for _, b := range decoded {
+		if b >= 32 && b < 127 {
+			printable.WriteByte(b)
+		}
+	}
+
+	return printable.String()
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte(" \t\r\n\f()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,152 @@
+package providers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func readResume(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile("../cmd/prepai-eval/fixtures/resume.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// Builds a single page PDF with the content stream, compressed when filter
+// is set.
+func singlePagePDF(t *testing.T, content string, filter string) []byte {
+	t.Helper()
+
+	stream := []byte(content)
+	dictionary := fmt.Sprintf("<< /Length %v >>", len(stream))
+	if filter != "" {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		_, err := writer.Write(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.Close()
+
+		stream = compressed.Bytes()
+		dictionary = fmt.Sprintf("<< /Length %v /Filter /%v >>", len(stream), filter)
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n%v\nstream\r\n", dictionary)
+	pdf.Write(stream)
+	pdf.WriteString("\r\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+
+	return pdf.Bytes()
+}
+
+const resumeContent = "BT /F1 12 Tf 72 720 Td (Jane Doe - Backend developer - Go, PostgreSQL, REST) Tj ET"
+
+func TestExtractPDFText(t *testing.T) {
+	tests := []struct {
+		name string
+		data func(t *testing.T) []byte
+		want string
+	}{
+		{
+			"resume",
+			readResume,
+			"Jane Doe - Backend developer - Go, PostgreSQL, REST",
+		},
+		{
+			"plain stream",
+			func(t *testing.T) []byte {
+				return singlePagePDF(t, resumeContent, "")
+			},
+			"Jane Doe - Backend developer - Go, PostgreSQL, REST",
+		},
+		{
+			"compressed stream",
+			func(t *testing.T) []byte {
+				return singlePagePDF(t, "BT /F1 12 Tf 72 720 Td (Jane Doe) Tj 0 -14 Td [(Go)-300(developer)] TJ ET", "FlateDecode")
+			},
+			"Jane Doe \nGo developer",
+		},
+		{
+			"escapes and hex strings",
+			func(t *testing.T) []byte {
+				return singlePagePDF(t, `BT (Senior \(lead\) engineer\101) Tj T* <004A006F00620073> Tj ET`, "FlateDecode")
+			},
+			"Senior (lead) engineerA \nJobs",
+		},
+		{
+			"other filters are skipped",
+			func(t *testing.T) []byte {
+				return singlePagePDF(t, "BT (image) Tj ET", "DCTDecode")
+			},
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ExtractPDFText(test.data(t))
+			if got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// Uploads are untrusted, broken files must give whatever text is readable
+// instead of taking the server down.
+func TestExtractPDFTextMalformed(t *testing.T) {
+	compressed := singlePagePDF(t, resumeContent, "FlateDecode")
+
+	// The compressed data cut short but still closed by endstream
+	start := bytes.Index(compressed, []byte("stream\r\n")) + len("stream\r\n")
+	truncated := append(bytes.Clone(compressed[:start+10]), "\r\nendstream\nendobj\n"...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not a pdf", []byte("hello world")},
+		{"stream keyword at start", []byte("stream")},
+		{"stream keyword at end", []byte("%PDF-1.4\n1 0 obj\n<< >>\nstream")},
+		{"stream without end", []byte("1 0 obj\n<< >>\nstream\nBT (Jane) Tj ET")},
+		{"endstream only", []byte("endstream endstream")},
+		{"bad compressed data", []byte("1 0 obj\n<< /Filter /FlateDecode >>\nstream\nnot zlib\nendstream\n")},
+		{"truncated compressed data", truncated},
+		{"unclosed literal", []byte("stream\nBT (Jane Doe\nendstream")},
+		{"trailing backslash", []byte("stream\nBT (Jane\\\nendstream")},
+		{"escape at end", []byte("stream\nBT (Jane\\")},
+		{"octal escape at end", []byte("stream\nBT (\\12\nendstream")},
+		{"unclosed hex string", []byte("stream\nBT <4A61\nendstream")},
+		{"odd hex string", []byte("stream\nBT <4A6> Tj ET\nendstream")},
+		{"invalid hex string", []byte("stream\nBT <zz> Tj ET\nendstream")},
+		{"unclosed comment", []byte("stream\nBT % comment\nendstream")},
+		{"name at end", []byte("stream\nBT /F1\nendstream")},
+		{"number at end", []byte("stream\nBT [(a) -300.5\nendstream")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ExtractPDFText(test.data)
+		})
+	}
+
+	// Every truncation of a valid file, like an interrupted upload
+	for _, data := range [][]byte{readResume(t), compressed} {
+		for end := range data {
+			ExtractPDFText(data[:end])
+		}
+	}
+}
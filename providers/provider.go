@@ -0,0 +1,130 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+)
+
+// Part is a piece of the prompt sent to the model, either text or an inline
+// file such as a PDF resume.
+type Part struct {
+	Text     string
+	MIMEType string
+	Data     []byte
+}
+
+const (
+	FeatureExam      = "exam"
+	FeatureInterview = "interview"
+	FeatureFeedback  = "feedback"
+	FeatureResume    = "resume"
+	FeatureQuestion  = "question"
+	FeatureModules   = "modules"
+	FeatureSteps     = "steps"
+	FeatureLesson    = "lesson"
+	FeatureTranslate = "translate"
+	FeatureGrade     = "grade"
+)
+
+var Features = []string{
+	FeatureExam,
+	FeatureInterview,
+	FeatureFeedback,
+	FeatureResume,
+	FeatureQuestion,
+	FeatureModules,
+	FeatureSteps,
+	FeatureLesson,
+	FeatureTranslate,
+	FeatureGrade,
+}
+
+type Request struct {
+	Feature string
+	// Instructions sent apart from the prompt, can be empty
+	System string
+	Parts  []Part
+}
+
+type Response struct {
+	Text             string
+	Model            string
+	PromptTokens     int32
+	CompletionTokens int32
+}
+
+// Provider generates JSON documents from a prompt. Every generator in
+// internal talks to the model through this interface.
+type Provider interface {
+	Generate(ctx context.Context, request Request) (Response, error)
+}
+
+// Modeler is implemented by providers that know which model answers a
+// feature before calling it.
+type Modeler interface {
+	Model(feature string) string
+}
+
+// ModelFor returns the model that will answer feature, or an empty string
+// when the provider does not say.
+func ModelFor(provider Provider, feature string) string {
+	if modeler, ok := provider.(Modeler); ok {
+		return modeler.Model(feature)
+	}
+
+	return ""
+}
+
+func Text(feature string, text string) Request {
+	return Request{
+		Feature: feature,
+		Parts:   []Part{{Text: text}},
+	}
+}
+
+// StatusError is returned by the HTTP backends when the server answers with
+// an error status.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("model server returned %v: %v", err.StatusCode, err.Message)
+}
+
+// Codes of the errors returned when the model could not answer.
+const (
+	// The model and its fallback keep failing or their circuit is open
+	CodeUnavailable = "unavailable"
+	// The call or the request it belongs to ran out of time
+	CodeTimeout = "timeout"
+	// Too many calls are waiting for the model
+	CodeBusy = "busy"
+	// The model server refused the call, retrying will not help
+	CodeFailed = "failed"
+)
+
+// Error is what callers get when generating failed on the model's side. The
+// message is safe to show to users, the cause is kept in Err.
+type Error struct {
+	Code string
+	Err  error
+}
+
+func (err *Error) Error() string {
+	switch err.Code {
+	case CodeTimeout:
+		return "the model took too long to answer, try again later"
+	case CodeUnavailable:
+		return "the model is unavailable right now, try again later"
+	case CodeBusy:
+		return "the model is busy right now, try again later"
+	default:
+		return "the model could not answer the request"
+	}
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
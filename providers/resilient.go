@@ -0,0 +1,228 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"prepai.app/configs"
+)
+
+var errCircuitOpen = errors.New("circuit open")
+
+// Resilient bounds every call to the primary provider with a timeout, retries
+// transient errors with jittered exponential backoff and stops calling a
+// model that keeps failing. While the primary is down the fallback, if any,
+// answers instead. Failures come out as *Error.
+type Resilient struct {
+	primary  *circuit
+	fallback *circuit
+	config   configs.ResilienceConfig
+}
+
+// A provider and the breaker that guards it.
+type circuit struct {
+	provider Provider
+	breaker  breaker
+}
+
+func NewResilient(primary Provider, fallback Provider, config configs.ResilienceConfig) *Resilient {
+	resilient := &Resilient{
+		primary: &circuit{provider: primary, breaker: breaker{threshold: config.BreakerThreshold, cooldown: config.BreakerCooldown}},
+		config:  config,
+	}
+	if fallback != nil {
+		resilient.fallback = &circuit{provider: fallback, breaker: breaker{threshold: config.BreakerThreshold, cooldown: config.BreakerCooldown}}
+	}
+
+	return resilient
+}
+
+func (resilient *Resilient) Model(feature string) string {
+	return ModelFor(resilient.primary.provider, feature)
+}
+
+func (resilient *Resilient) Generate(ctx context.Context, request Request) (Response, error) {
+	return resilient.call(ctx, request.Feature, func(ctx context.Context, provider Provider) (Response, error) {
+		return provider.Generate(ctx, request)
+	}, nil)
+}
+
+// Stream is only retried while nothing was sent to onChunk, chunks already
+// handed out cannot be taken back.
+func (resilient *Resilient) Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error) {
+	sent := false
+	return resilient.call(ctx, request.Feature, func(ctx context.Context, provider Provider) (Response, error) {
+		return Stream(ctx, provider, request, func(text string) {
+			sent = true
+			onChunk(text)
+		})
+	}, func() bool {
+		return !sent
+	})
+}
+
+func (resilient *Resilient) call(ctx context.Context, feature string, do func(ctx context.Context, provider Provider) (Response, error), canRetry func() bool) (Response, error) {
+	if canRetry == nil {
+		canRetry = func() bool { return true }
+	}
+
+	response, err := resilient.try(ctx, resilient.primary, do, canRetry)
+	if err == nil {
+		return response, nil
+	}
+
+	if resilient.fallback != nil && ctx.Err() == nil && canRetry() && (errors.Is(err, errCircuitOpen) || errors.Is(err, errBusy) || isTransient(err)) {
+		log.Printf("%v: model %v failed, using fallback %v: %v", feature, resilient.Model(feature), ModelFor(resilient.fallback.provider, feature), err)
+		response, err = resilient.try(ctx, resilient.fallback, do, canRetry)
+		if err == nil {
+			return response, nil
+		}
+	}
+
+	return Response{}, resilient.wrap(ctx, feature, err)
+}
+
+func (resilient *Resilient) try(ctx context.Context, circuit *circuit, do func(ctx context.Context, provider Provider) (Response, error), canRetry func() bool) (Response, error) {
+	for attempt := 0; ; attempt++ {
+		if !circuit.breaker.allow() {
+			return Response{}, errCircuitOpen
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, resilient.config.Timeout)
+		response, err := do(callCtx, circuit.provider)
+		cancel()
+
+		if err == nil {
+			circuit.breaker.succeed()
+			return response, nil
+		}
+		// The caller gave up or the call never reached the model, that says
+		// nothing about it
+		if ctx.Err() != nil || errors.Is(err, errBusy) {
+			circuit.breaker.release()
+			return Response{}, err
+		}
+		// The server answered, it is up even if it refused the call
+		if !isTransient(err) {
+			circuit.breaker.succeed()
+			return Response{}, err
+		}
+
+		circuit.breaker.fail()
+		if attempt >= resilient.config.MaxRetries || !canRetry() {
+			return Response{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		case <-time.After(resilient.backoff(attempt)):
+		}
+	}
+}
+
+// Exponential backoff with equal jitter so callers failing together do not
+// retry together.
+func (resilient *Resilient) backoff(attempt int) time.Duration {
+	delay := resilient.config.BaseDelay << attempt
+	if delay <= 0 || delay > resilient.config.MaxDelay {
+		delay = resilient.config.MaxDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+func (resilient *Resilient) wrap(ctx context.Context, feature string, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+
+	log.Printf("%v: model call failed: %v", feature, err)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeTimeout, Err: err}
+	case errors.Is(err, errBusy):
+		return &Error{Code: CodeBusy, Err: err}
+	case errors.Is(err, errCircuitOpen) || isTransient(err):
+		return &Error{Code: CodeUnavailable, Err: err}
+	default:
+		return &Error{Code: CodeFailed, Err: err}
+	}
+}
+
+// Transient errors may go away by calling again: rate limits, server errors,
+// timeouts and dropped connections.
+func isTransient(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+// breaker opens after threshold consecutive failures. Once cooldown passes a
+// single call is let through, its outcome closes or reopens the circuit. A
+// zero threshold disables it.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (breaker *breaker) allow() bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if breaker.threshold == 0 || breaker.failures < breaker.threshold {
+		return true
+	}
+	if time.Now().Before(breaker.openUntil) || breaker.probing {
+		return false
+	}
+
+	breaker.probing = true
+	return true
+}
+
+func (breaker *breaker) succeed() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.failures = 0
+	breaker.probing = false
+}
+
+func (breaker *breaker) fail() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.failures++
+	breaker.probing = false
+	if breaker.threshold > 0 && breaker.failures >= breaker.threshold {
+		breaker.openUntil = time.Now().Add(breaker.cooldown)
+	}
+}
+
+// Lets another call probe the circuit when this one ended without telling
+// whether the model is up.
+func (breaker *breaker) release() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.probing = false
+}
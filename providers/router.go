@@ -0,0 +1,135 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"prepai.app/configs"
+)
+
+// Router sends each request to the provider configured for its feature,
+// falling back to the default one.
+type Router struct {
+	fallback Provider
+	features map[string]Provider
+	limiter  *Limiter
+}
+
+func NewRouter(fallback Provider) *Router {
+	return &Router{
+		fallback: fallback,
+		features: make(map[string]Provider),
+	}
+}
+
+func (router *Router) Route(feature string, provider Provider) {
+	router.features[feature] = provider
+}
+
+func (router *Router) Generate(ctx context.Context, request Request) (Response, error) {
+	if provider, ok := router.features[request.Feature]; ok {
+		return provider.Generate(ctx, request)
+	}
+
+	return router.fallback.Generate(ctx, request)
+}
+
+func (router *Router) Model(feature string) string {
+	if provider, ok := router.features[feature]; ok {
+		return ModelFor(provider, feature)
+	}
+
+	return ModelFor(router.fallback, feature)
+}
+
+func (router *Router) Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error) {
+	if provider, ok := router.features[request.Feature]; ok {
+		return Stream(ctx, provider, request, onChunk)
+	}
+
+	return Stream(ctx, router.fallback, request, onChunk)
+}
+
+// Limiter returns the limiter shared by the routed providers, nil when the
+// router was not built by NewFromEnv.
+func (router *Router) Limiter() *Limiter {
+	return router.limiter
+}
+
+func NewFromConfig(config configs.LLMConfig) (Provider, error) {
+	switch config.Backend {
+	case configs.BackendGemini:
+		geminiConfig := configs.GetGeminiConfig()
+		if config.Model != "" {
+			geminiConfig.ModelName = config.Model
+		}
+		if config.APIKey != "" {
+			geminiConfig.APIKey = config.APIKey
+		}
+		if config.Temperature != nil {
+			geminiConfig.Temperature = config.Temperature
+		}
+		if config.MaxOutputTokens != nil {
+			geminiConfig.MaxOutputTokens = config.MaxOutputTokens
+		}
+		return NewGemini(geminiConfig)
+	case configs.BackendOpenAI:
+		return NewOpenAI(config), nil
+	case configs.BackendOllama:
+		return NewOllama(config), nil
+	default:
+		return nil, fmt.Errorf("unknown llm backend %v", config.Backend)
+	}
+}
+
+// Builds the router from the environment. Every provider is wrapped in
+// Resilient, features sharing the same settings share the same instance and
+// so the same circuit breakers. Calls to every model go through one Limiter.
+func NewFromEnv() (*Router, error) {
+	resilience := configs.GetResilienceConfig()
+	limiter := NewLimiter(configs.GetLimiterConfig())
+	instances := make(map[string]Provider)
+	build := func(config configs.LLMConfig) (Provider, error) {
+		key := fmt.Sprintf("%v|%v|%v|%v|%v|%v|%v", config.Backend, config.BaseURL, config.APIKey, config.Model, config.FallbackModel, *config.Temperature, *config.MaxOutputTokens)
+		if provider, ok := instances[key]; ok {
+			return provider, nil
+		}
+
+		primary, err := NewFromConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		var fallback Provider
+		if config.FallbackModel != "" && config.FallbackModel != config.Model {
+			fallbackConfig := config
+			fallbackConfig.Model = config.FallbackModel
+			fallbackProvider, err := NewFromConfig(fallbackConfig)
+			if err != nil {
+				return nil, err
+			}
+			fallback = limiter.Wrap(fallbackProvider)
+		}
+
+		provider := NewResilient(limiter.Wrap(primary), fallback, resilience)
+		instances[key] = provider
+		return provider, nil
+	}
+
+	fallback, err := build(configs.GetLLMConfig(""))
+	if err != nil {
+		return nil, err
+	}
+
+	router := NewRouter(fallback)
+	router.limiter = limiter
+	for _, feature := range Features {
+		provider, err := build(configs.GetLLMConfig(feature))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", feature, err)
+		}
+		router.Route(feature, provider)
+	}
+
+	return router, nil
+}
@@ -0,0 +1,56 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"prepai.app/configs"
+	"prepai.app/providers"
+)
+
+// A proxy in front of the model server answers with HTML while the server is
+// down, it must still surface as a StatusError so the call is retried.
+func TestUnavailableServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("<html><body><h1>503 Service Temporarily Unavailable</h1></body></html>"))
+	}))
+	defer server.Close()
+
+	config := configs.LLMConfig{BaseURL: server.URL, Model: "test"}
+	tests := []struct {
+		name     string
+		provider providers.Provider
+	}{
+		{"openai", providers.NewOpenAI(config)},
+		{"ollama", providers.NewOllama(config)},
+	}
+
+	request := providers.Request{Feature: providers.FeatureExam, Parts: []providers.Part{{Text: "Create an exam."}}}
+	for _, test := range tests {
+		calls := map[string]func() error{
+			"generate": func() error {
+				_, err := test.provider.Generate(context.Background(), request)
+				return err
+			},
+			"stream": func() error {
+				_, err := providers.Stream(context.Background(), test.provider, request, func(string) {})
+				return err
+			},
+		}
+
+		for name, call := range calls {
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				var statusError *providers.StatusError
+				err := call()
+				if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusServiceUnavailable {
+					t.Fatalf("got %v, want a 503 StatusError", err)
+				}
+			})
+		}
+	}
+}
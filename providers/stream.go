@@ -0,0 +1,25 @@
+package providers
+
+import "context"
+
+// Streamer is implemented by providers that can hand out the response while
+// the model is still writing it.
+type Streamer interface {
+	Stream(ctx context.Context, request Request, onChunk func(text string)) (Response, error)
+}
+
+// Stream uses the provider's streaming API when it has one. Otherwise the
+// whole response is delivered as a single chunk once it is ready.
+func Stream(ctx context.Context, provider Provider, request Request, onChunk func(text string)) (Response, error) {
+	if streamer, ok := provider.(Streamer); ok {
+		return streamer.Stream(ctx, request, onChunk)
+	}
+
+	response, err := provider.Generate(ctx, request)
+	if err != nil {
+		return Response{}, err
+	}
+
+	onChunk(response.Text)
+	return response, nil
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"prepai.app/controllers"
+	"prepai.app/middlewares"
+)
+
+func ActivityRoute(server *gin.Engine) {
+	authActivity := server.Group("/activities")
+	authActivity.Use(middlewares.Authenticate)
+
+	// GET
+	authActivity.GET("", controllers.GetActivities)
+	authActivity.GET("/:id", controllers.GetActivity)
+	authActivity.GET("/:id/content", controllers.GetActivityContent)
+	// POST
+	authActivity.POST("", controllers.CreateActivity)
+	authActivity.POST("/:id/complete", controllers.CompleteActivity)
+	// DELETE
+	authActivity.DELETE("/:id", controllers.DeleteActivity)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"prepai.app/controllers"
+	"prepai.app/middlewares"
+)
+
+func AdminRoute(server *gin.Engine) {
+	admin := server.Group("/admin")
+	admin.Use(middlewares.Authenticate, middlewares.RequireAdmin)
+
+	// GET
+	admin.GET("/experiments", controllers.GetExperiments)
+	admin.GET("/models", controllers.GetModelStats)
+	admin.GET("/exams/positions", controllers.GetCorrectPositionStats)
+}
@@ -14,14 +14,23 @@ func ExamRoute(server *gin.Engine) {
 	authExam.GET("", controllers.GetExams)
 	authExam.GET("/:id", controllers.GetExam)
 	authExam.GET("/:id/attempt", controllers.GetExamAttempt)
+	authExam.GET("/:id/attempts", controllers.GetExamAttempts)
+	authExam.GET("/:id/attempts/:attemptId", controllers.GetExamAttempt)
 	// POST
 	authExam.POST("", controllers.CreateExam)
+	authExam.POST("/stream", controllers.StreamExam)
 	authExam.POST("/:id/attempt", controllers.CreateExamAttempt)
+	authExam.POST("/:id/attempts", controllers.CreateExamAttempt)
+	authExam.POST("/:id/flag", controllers.FlagExam)
+	authExam.POST("/:id/translate", controllers.TranslateExam)
 
 	// PATCH
 	authExam.PATCH("/:id", controllers.UpdateExam)
 	authExam.PATCH("/:id/regenerate", controllers.RegenerateExam)
+	authExam.PATCH("/:id/attempt/answers", controllers.SaveExamAttemptAnswers)
 	authExam.PATCH("/:id/attempt/submit", controllers.SubmitExamAttempt)
+	authExam.PATCH("/:id/attempts/:attemptId/answers", controllers.SaveExamAttemptAnswers)
+	authExam.PATCH("/:id/attempts/:attemptId/submit", controllers.SubmitExamAttempt)
 	// DELETE
 	authExam.DELETE("/:id", controllers.DeleteExam)
 }
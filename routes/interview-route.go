@@ -17,10 +17,14 @@ func InterviewRoute(server *gin.Engine) {
 	// POST
 	authInterview.POST("", controllers.CreateInterview)
 	authInterview.POST("/:id/attempt", controllers.CreateInterviewAttempt)
+	authInterview.POST("/:id/flag", controllers.FlagInterview)
+	authInterview.POST("/:id/attempt/flag", controllers.FlagInterviewFeedback)
+	authInterview.POST("/:id/translate", controllers.TranslateInterview)
 	// PATCH
 	authInterview.PATCH("/:id", controllers.UpdateInterview)
 	authInterview.PATCH("/:id/regenerate", controllers.RegenerateInterview)
 	authInterview.PATCH("/:id/attempt/feedback", controllers.CreateInterviewAttemptFeedback)
+	authInterview.PATCH("/:id/attempt/feedback/stream", controllers.StreamInterviewAttemptFeedback)
 	// DELETE
 	authInterview.DELETE("/:id", controllers.DeleteInterview)
 }
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"prepai.app/controllers"
+	"prepai.app/middlewares"
+)
+
+func JobRoute(server *gin.Engine) {
+	authJob := server.Group("/jobs")
+	authJob.Use(middlewares.Authenticate)
+
+	// GET
+	authJob.GET("/:id", controllers.GetJob)
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"prepai.app/controllers"
+	"prepai.app/middlewares"
+)
+
+func LearningPathRoute(server *gin.Engine) {
+	authPath := server.Group("/paths")
+	authPath.Use(middlewares.Authenticate)
+
+	// GET
+	authPath.GET("", controllers.GetLearningPaths)
+	authPath.GET("/:id", controllers.GetLearningPath)
+	authPath.GET("/:id/progress", controllers.GetLearningPathProgress)
+	// POST
+	authPath.POST("", controllers.CreateLearningPath)
+	authPath.POST("/:id/modules/:order/steps", controllers.GenerateModuleSteps)
+	// PATCH
+	authPath.PATCH("/:id", controllers.UpdateLearningPath)
+	// DELETE
+	authPath.DELETE("/:id", controllers.DeleteLearningPath)
+}
@@ -15,6 +15,7 @@ func ResumeRoute(server *gin.Engine) {
 	authResume.GET("/:id", controllers.GetResume)
 	// POST
 	authResume.POST("", controllers.CreateResume)
+	authResume.POST("/stream", controllers.StreamResume)
 	// DELETE
 	authResume.DELETE("/:id", controllers.DeleteResume)
 }
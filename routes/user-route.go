@@ -10,6 +10,7 @@ func UserRoute(server *gin.Engine) {
 	authenticatedRoute := server.Group("/user")
 	authenticatedRoute.Use(middlewares.Authenticate)
 	authenticatedRoute.GET("", controllers.GetUser)
+	authenticatedRoute.GET("/usage", controllers.GetUserUsage)
 	authenticatedRoute.PATCH("/update", controllers.UpdateUser)
 	authenticatedRoute.DELETE("/delete", controllers.DeleteUser)
 }